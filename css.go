@@ -0,0 +1,508 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"fmt"
+	"golang.org/x/net/html"
+	"strings"
+)
+
+// CompiledSelector is a parsed CSS selector. It is safe for concurrent use and should be reused
+// instead of compiling the same selector over and over again.
+//
+// The supported syntax consists of
+//   - type selectors (div) and the universal selector (*)
+//   - id (#main) and class (.product) selectors
+//   - attribute selectors ([href], [lang=en], [class~=item], [lang|=en], [href^=https], [href$=".pdf"],
+//     [href*=example]), optionally with the i flag for case-insensitive values ([type=text i])
+//   - the descendant ( ), child (>), next sibling (+) and subsequent sibling (~) combinators
+//   - selector lists (h1, h2), matched in document order without duplicates
+//   - the :first-child and :last-child pseudo-classes, which match elements without preceding
+//     or following element siblings
+//   - the :contains(text) pseudo-class, which matches elements whose text as returned by TextContentR
+//     contains the argument. The argument may be quoted.
+type CompiledSelector struct {
+	source string
+	groups []complexSelector
+}
+
+// Compile parses a CSS selector.
+func Compile(selector string) (*CompiledSelector, error) {
+	p := &selectorParser{s: selector}
+	groups, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &CompiledSelector{source: selector, groups: groups}, nil
+}
+
+// MustCompile is like Compile but panics if the selector can't be parsed.
+func MustCompile(selector string) *CompiledSelector {
+	cs, err := Compile(selector)
+	if err != nil {
+		panic(err)
+	}
+	return cs
+}
+
+// ClosestStr returns the node itself or its nearest ancestor that matches the CSS selector or nil if there is none.
+func (n *Node) ClosestStr(selector string) (*Node, error) {
+	cs, err := Compile(selector)
+	if err != nil {
+		return nil, err
+	}
+	if res := closest(n.backing, cs.matches); res != nil {
+		return newNode(res), nil
+	}
+	return nil, nil
+}
+
+// Find returns all descendants that match the CSS selector in document order.
+// Use Compile to reuse a selector for many queries.
+func (n *Node) Find(selector string) ([]*Node, error) {
+	cs, err := Compile(selector)
+	if err != nil {
+		return nil, err
+	}
+	return cs.SelectAll(n), nil
+}
+
+// FindOne returns the first descendant in document order that matches the CSS selector or nil if there is none.
+func (n *Node) FindOne(selector string) (*Node, error) {
+	cs, err := Compile(selector)
+	if err != nil {
+		return nil, err
+	}
+	return cs.SelectFirst(n), nil
+}
+
+// Matches returns true if n itself matches the selector.
+func (cs *CompiledSelector) Matches(n *Node) bool {
+	return cs.matches(n.backing)
+}
+
+// SelectAll returns all descendants of n that match the selector in document order.
+func (cs *CompiledSelector) SelectAll(n *Node) []*Node {
+	return newNodes(selectAll(n.backing, cs.matches, true, 0))
+}
+
+// SelectFirst returns the first descendant of n in document order that matches the selector.
+func (cs *CompiledSelector) SelectFirst(n *Node) *Node {
+	res := selectAll(n.backing, cs.matches, true, 1)
+	if len(res) > 0 {
+		return newNode(res[0])
+	}
+	return nil
+}
+
+func (cs *CompiledSelector) String() string {
+	return cs.source
+}
+
+func (cs *CompiledSelector) matches(node *html.Node) bool {
+	for _, g := range cs.groups {
+		if g.matches(node, len(g.parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// complexSelector is a sequence of compound selectors joined by combinators.
+// combinators[i] joins parts[i] and parts[i+1].
+type complexSelector struct {
+	parts       []compoundSelector
+	combinators []byte
+}
+
+// matches returns true if node matches parts[i] and its context matches the parts to the left.
+func (c complexSelector) matches(node *html.Node, i int) bool {
+	if !c.parts[i].matches(node) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch c.combinators[i-1] {
+	case '>':
+		return node.Parent != nil && c.matches(node.Parent, i-1)
+	case '+':
+		prev := prevElementSibling(node)
+		return prev != nil && c.matches(prev, i-1)
+	case '~':
+		for prev := prevElementSibling(node); prev != nil; prev = prevElementSibling(prev) {
+			if c.matches(prev, i-1) {
+				return true
+			}
+		}
+		return false
+	}
+	for p := node.Parent; p != nil; p = p.Parent {
+		if c.matches(p, i-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// compoundSelector is a type selector combined with simple selectors, e.g. a.nav#top.
+type compoundSelector struct {
+	// tag is the tag name or an empty string for any element
+	tag        string
+	conditions []func(*html.Node) bool
+}
+
+func (c compoundSelector) matches(node *html.Node) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+	if len(c.tag) > 0 && !strings.EqualFold(node.Data, c.tag) {
+		return false
+	}
+	for _, cond := range c.conditions {
+		if !cond(node) {
+			return false
+		}
+	}
+	return true
+}
+
+func prevElementSibling(node *html.Node) *html.Node {
+	for s := node.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+func nextElementSibling(node *html.Node) *html.Node {
+	for s := node.NextSibling; s != nil; s = s.NextSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+type selectorParser struct {
+	s   string
+	pos int
+}
+
+func (p *selectorParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("soup: invalid selector %q at offset %d: %s", p.s, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *selectorParser) parse() ([]complexSelector, error) {
+	groups := make([]complexSelector, 0, 1)
+	for {
+		p.skipWhitespace()
+		g, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+		if p.pos == len(p.s) {
+			return groups, nil
+		}
+		// parseComplex only stops early at a comma
+		p.pos++
+	}
+}
+
+func (p *selectorParser) parseComplex() (complexSelector, error) {
+	var c complexSelector
+	for {
+		compound, err := p.parseCompound()
+		if err != nil {
+			return c, err
+		}
+		c.parts = append(c.parts, compound)
+		hadWhitespace := p.skipWhitespace()
+		if p.pos == len(p.s) || p.s[p.pos] == ',' {
+			return c, nil
+		}
+		switch comb := p.s[p.pos]; comb {
+		case '>', '+', '~':
+			p.pos++
+			p.skipWhitespace()
+			c.combinators = append(c.combinators, comb)
+		default:
+			if !hadWhitespace {
+				return c, p.errorf("unexpected %q", comb)
+			}
+			c.combinators = append(c.combinators, ' ')
+		}
+	}
+}
+
+func (p *selectorParser) parseCompound() (compoundSelector, error) {
+	var c compoundSelector
+	start := p.pos
+	if p.peek('*') {
+		p.pos++
+	} else if p.pos < len(p.s) && isIdentChar(p.s[p.pos]) {
+		c.tag = strings.ToLower(p.parseIdent())
+	}
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '#':
+			p.pos++
+			id := p.parseIdent()
+			if len(id) == 0 {
+				return c, p.errorf("expected id")
+			}
+			c.conditions = append(c.conditions, func(n *html.Node) bool {
+				return Attr(n, "id") == id
+			})
+		case '.':
+			p.pos++
+			className := p.parseIdent()
+			if len(className) == 0 {
+				return c, p.errorf("expected class name")
+			}
+			c.conditions = append(c.conditions, func(n *html.Node) bool {
+				return HasClass(n, className)
+			})
+		case '[':
+			p.pos++
+			cond, err := p.parseAttribute()
+			if err != nil {
+				return c, err
+			}
+			c.conditions = append(c.conditions, cond)
+		case ':':
+			p.pos++
+			cond, err := p.parsePseudo()
+			if err != nil {
+				return c, err
+			}
+			c.conditions = append(c.conditions, cond)
+		default:
+			if p.pos == start {
+				return c, p.errorf("expected selector")
+			}
+			return c, nil
+		}
+	}
+	if p.pos == start {
+		return c, p.errorf("expected selector")
+	}
+	return c, nil
+}
+
+func (p *selectorParser) parsePseudo() (func(*html.Node) bool, error) {
+	name := strings.ToLower(p.parseIdent())
+	switch name {
+	case "contains":
+		arg, err := p.parseArgument()
+		if err != nil {
+			return nil, err
+		}
+		return func(n *html.Node) bool {
+			return strings.Contains(TextContentR(n), arg)
+		}, nil
+	case "first-child":
+		return func(n *html.Node) bool {
+			return prevElementSibling(n) == nil
+		}, nil
+	case "last-child":
+		return func(n *html.Node) bool {
+			return nextElementSibling(n) == nil
+		}, nil
+	case "":
+		return nil, p.errorf("expected pseudo-class")
+	}
+	return nil, p.errorf("unsupported pseudo-class :%s", name)
+}
+
+// parseArgument parses a parenthesized pseudo-class argument, which is either a quoted string
+// or raw text up to the closing parenthesis. Backslash escapes the next character in both.
+func (p *selectorParser) parseArgument() (string, error) {
+	if !p.peek('(') {
+		return "", p.errorf("expected (")
+	}
+	p.pos++
+	p.skipWhitespace()
+	if p.peek('"') || p.peek('\'') {
+		arg, err := p.parseString()
+		if err != nil {
+			return "", err
+		}
+		p.skipWhitespace()
+		if !p.peek(')') {
+			return "", p.errorf("expected )")
+		}
+		p.pos++
+		return arg, nil
+	}
+	var b strings.Builder
+	for {
+		if p.pos == len(p.s) {
+			return "", p.errorf("expected )")
+		}
+		ch := p.s[p.pos]
+		p.pos++
+		if ch == ')' {
+			break
+		}
+		if ch == '\\' && p.pos < len(p.s) {
+			ch = p.s[p.pos]
+			p.pos++
+		}
+		b.WriteByte(ch)
+	}
+	return strings.TrimRight(b.String(), " \t\n\r\f"), nil
+}
+
+// parseAttribute parses an attribute selector after the opening bracket.
+// Attribute names match case-insensitively, since the parser keeps the camelCase names of SVG attributes like viewBox.
+func (p *selectorParser) parseAttribute() (func(*html.Node) bool, error) {
+	p.skipWhitespace()
+	key := p.parseIdent()
+	if len(key) == 0 {
+		return nil, p.errorf("expected attribute name")
+	}
+	p.skipWhitespace()
+	if p.peek(']') {
+		p.pos++
+		return func(n *html.Node) bool {
+			for _, a := range n.Attr {
+				if strings.EqualFold(a.Key, key) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+	op := ""
+	for _, o := range []string{"=", "~=", "|=", "^=", "$=", "*="} {
+		if strings.HasPrefix(p.s[p.pos:], o) {
+			op = o
+			break
+		}
+	}
+	if len(op) == 0 {
+		return nil, p.errorf("expected attribute operator")
+	}
+	p.pos += len(op)
+	p.skipWhitespace()
+	var val string
+	if p.peek('"') || p.peek('\'') {
+		var err error
+		if val, err = p.parseString(); err != nil {
+			return nil, err
+		}
+	} else if val = p.parseIdent(); len(val) == 0 {
+		return nil, p.errorf("expected attribute value")
+	}
+	p.skipWhitespace()
+	fold := false
+	if p.peek('i') || p.peek('I') {
+		fold = true
+		p.pos++
+		p.skipWhitespace()
+	}
+	if !p.peek(']') {
+		return nil, p.errorf("expected ]")
+	}
+	p.pos++
+	match := attrMatcher(op, val, fold)
+	return func(n *html.Node) bool {
+		for _, a := range n.Attr {
+			if strings.EqualFold(a.Key, key) {
+				return match(a.Val)
+			}
+		}
+		return false
+	}, nil
+}
+
+// attrMatcher returns a function matching attribute values against val with the given operator.
+func attrMatcher(op, val string, fold bool) func(string) bool {
+	if fold {
+		val = strings.ToLower(val)
+	}
+	return func(v string) bool {
+		if fold {
+			v = strings.ToLower(v)
+		}
+		switch op {
+		case "~=":
+			// a list of whitespace separated words, just like the class attribute
+			return containsClass(v, val)
+		case "|=":
+			return v == val || strings.HasPrefix(v, val+"-")
+		case "^=":
+			return len(val) > 0 && strings.HasPrefix(v, val)
+		case "$=":
+			return len(val) > 0 && strings.HasSuffix(v, val)
+		case "*=":
+			return len(val) > 0 && strings.Contains(v, val)
+		}
+		return v == val
+	}
+}
+
+// parseString parses a single or double quoted string. A backslash escapes the next character.
+func (p *selectorParser) parseString() (string, error) {
+	quote := p.s[p.pos]
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.pos == len(p.s) {
+			return "", p.errorf("unterminated string")
+		}
+		ch := p.s[p.pos]
+		p.pos++
+		if ch == quote {
+			return b.String(), nil
+		}
+		if ch == '\\' && p.pos < len(p.s) {
+			ch = p.s[p.pos]
+			p.pos++
+		}
+		b.WriteByte(ch)
+	}
+}
+
+// parseIdent parses an identifier. A backslash escapes the next character.
+func (p *selectorParser) parseIdent() string {
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		ch := p.s[p.pos]
+		if ch == '\\' && p.pos+1 < len(p.s) {
+			b.WriteByte(p.s[p.pos+1])
+			p.pos += 2
+			continue
+		}
+		if !isIdentChar(ch) {
+			break
+		}
+		b.WriteByte(ch)
+		p.pos++
+	}
+	return b.String()
+}
+
+func (p *selectorParser) peek(ch byte) bool {
+	return p.pos < len(p.s) && p.s[p.pos] == ch
+}
+
+// skipWhitespace advances past any whitespace and returns true if there was some.
+func (p *selectorParser) skipWhitespace() bool {
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte(" \t\n\r\f", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func isIdentChar(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' ||
+		ch == '-' || ch == '_' || ch >= 0x80
+}
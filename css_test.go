@@ -0,0 +1,196 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"fmt"
+	"golang.org/x/net/html"
+	"strings"
+	"testing"
+)
+
+func TestAttributeSelectors(t *testing.T) {
+	tests := []struct {
+		selector string
+		key, val string
+		want     bool
+	}{
+		{"[class]", "class", "", true},
+		{"[class]", "id", "foo", false},
+
+		{"[class=foo]", "class", "foo", true},
+		{"[class=foo]", "class", "foo bar", false},
+		{"[class=foo]", "class", "Foo", false},
+		{`[class="foo bar"]`, "class", "foo bar", true},
+
+		{"[class~=foo]", "class", "foo bar", true},
+		{"[class~=bar]", "class", "foo\tbar", true},
+		{"[class~=foo]", "class", "foobar", false},
+		{`[class~=""]`, "class", "foo", false},
+
+		{"[lang|=en]", "lang", "en", true},
+		{"[lang|=en]", "lang", "en-US", true},
+		{"[lang|=en]", "lang", "english", false},
+
+		{"[href^=https]", "href", "https://example.com", true},
+		{"[href^=https]", "href", "http://example.com", false},
+		{`[href^=""]`, "href", "https://example.com", false},
+
+		{`[href$=".pdf"]`, "href", "/doc.pdf", true},
+		{`[href$=".pdf"]`, "href", "/doc.pdf?x", false},
+		{`[href$=""]`, "href", "/doc.pdf", false},
+
+		{"[href*=example]", "href", "https://example.com", true},
+		{"[href*=example]", "href", "https://test.com", false},
+		{`[href*=""]`, "href", "https://example.com", false},
+
+		{"[type=text i]", "type", "TEXT", true},
+		{"[type=TEXT i]", "type", "text", true},
+		{"[type=text]", "type", "TEXT", false},
+		{"[class~=FOO i]", "class", "bar foo", true},
+		{"[lang|=EN i]", "lang", "en-us", true},
+		{"[href^=HTTPS i]", "href", "https://example.com", true},
+		{`[href$=".PDF" i]`, "href", "/doc.pdf", true},
+		{"[href*=EXAMPLE i]", "href", "https://example.com", true},
+
+		{"[CLASS=foo]", "class", "foo", true},
+		{"[viewBox]", "viewBox", "0 0 1 1", true},
+		{"[viewbox]", "viewBox", "0 0 1 1", true},
+		{"[viewBox='0 0 1 1']", "viewBox", "0 0 1 1", true},
+	}
+	for _, tt := range tests {
+		cs, err := Compile(tt.selector)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.selector, err)
+			continue
+		}
+		node := &html.Node{Type: html.ElementNode, Data: "a", Attr: []html.Attribute{{Key: tt.key, Val: tt.val}}}
+		if got := cs.matches(node); got != tt.want {
+			t.Errorf("%s matching %s=%q = %v, want %v", tt.selector, tt.key, tt.val, got, tt.want)
+		}
+	}
+}
+
+const selectorDocument = `
+<div id="main" class="content">
+	<h1 id="title">Soup</h1>
+	<ul id="list">
+		<li id="li1" class="item">one</li>
+		<li id="li2" class="item special">two (2)</li>
+		<li id="li3"><ul id="inner"><li id="li4">it's four</li></ul></li>
+	</ul>
+	<p id="p1">first</p>
+	<p id="p2">second</p>
+</div>
+<footer id="footer"><a id="link" href="https://example.com/a.pdf">pdf</a></footer>`
+
+func TestCompiledSelectorSelectAll(t *testing.T) {
+	root, err := Parse(strings.NewReader(selectorDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"li", "li1 li2 li3 li4"},
+		{"LI", "li1 li2 li3 li4"},
+		{"#title", "title"},
+		{".item", "li1 li2"},
+		{".item.special", "li2"},
+		{"li.item:last-child", ""},
+		{"li:first-child", "li1 li4"},
+		{"li:last-child", "li3 li4"},
+		{"div li", "li1 li2 li3 li4"},
+		{"#list > li", "li1 li2 li3"},
+		{"ul > li > ul > li", "li4"},
+		{"#li1 + li", "li2"},
+		{"#li1 ~ li", "li2 li3"},
+		{"h1 ~ p", "p1 p2"},
+		{"h1 + p", ""},
+		{"ul#list>li.item", "li1 li2"},
+		{"  #p1  ,  #title  ", "title p1"},
+		{"p, h1", "title p1 p2"},
+		{"li, ul li", "li1 li2 li3 li4"},
+		{"#p2, p, *#p2", "p1 p2"},
+		{"footer [href$='.pdf']", "link"},
+		{"*[id=main]", "main"},
+		{"li:contains(two)", "li2"},
+		{"li:contains('two (2)')", "li2"},
+		{`li:contains("it's")`, "li3 li4"},
+		{`li:contains(it\'s four)`, "li3 li4"},
+		{`li:contains(\(2\))`, "li2"},
+		{"li:contains('(2)')", "li2"},
+		{"li:contains(nothing)", ""},
+	}
+	for _, tt := range tests {
+		cs, err := Compile(tt.selector)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.selector, err)
+			continue
+		}
+		ids := make([]string, 0)
+		for _, n := range cs.SelectAll(root) {
+			ids = append(ids, n.Attr("id"))
+		}
+		if got := strings.Join(ids, " "); got != tt.want {
+			t.Errorf("%s selected %q, want %q", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestAttributeSelectorsOnSVG(t *testing.T) {
+	root, err := Parse(strings.NewReader(`<svg viewBox="0 0 1 1"><path d="M0 0"/></svg>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, selector := range []string{"[viewBox]", "svg[viewBox]", "[viewbox]", "svg[viewBox^='0 0']"} {
+		if got := len(MustCompile(selector).SelectAll(root)); got != 1 {
+			t.Errorf("%s selected %d nodes, want 1", selector, got)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		selector string
+		offset   int
+	}{
+		{"", 0},
+		{"div >", 5},
+		{"a,", 2},
+		{",a", 0},
+		{"a >> b", 3},
+		{"a b)", 3},
+		{"..x", 1},
+		{"[=x]", 1},
+		{"[href", 5},
+		{"[a~b]", 2},
+		{"a:nope", 6},
+		{":contains(x", 11},
+		{"a:contains('b)", 14},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.selector)
+		if err == nil {
+			t.Errorf("Compile(%q) succeeded, want an error", tt.selector)
+			continue
+		}
+		if want := fmt.Sprintf("at offset %d:", tt.offset); !strings.Contains(err.Error(), want) {
+			t.Errorf("Compile(%q) = %v, want an error %s", tt.selector, err, want)
+		}
+	}
+}
+
+func TestCompileContainsWithQuotedParenthesis(t *testing.T) {
+	cs, err := Compile("a:contains('b)c')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, _ := ParseFragmentString(`<a>xb)cx</a><a>b</a>`, "")
+	if !cs.Matches(node[0]) || cs.Matches(node[1]) {
+		t.Errorf("%s doesn't match the text b)c", cs)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"fmt"
+	"golang.org/x/net/html"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+const (
+	// ElementAdded means that the element only exists in the new document.
+	ElementAdded ChangeKind = iota
+	// ElementRemoved means that the element only exists in the old document.
+	ElementRemoved
+	// ElementModified means that the element exists in both documents but its attributes or its own text differ.
+	ElementModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ElementAdded:
+		return "added"
+	case ElementRemoved:
+		return "removed"
+	case ElementModified:
+		return "modified"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// Change describes a difference between two documents as reported by Diff.
+type Change struct {
+	Kind ChangeKind
+	// Path locates the element like an XPath, e.g. "/html[1]/body[1]/div[2]/p[1]".
+	// The index counts the element siblings with the same tag, starting at 1.
+	Path string
+	// Old is the element in the old document or nil if it was added.
+	Old *Node
+	// New is the element in the new document or nil if it was removed.
+	New *Node
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s", c.Kind, c.Path)
+}
+
+// Diff compares the elements of two documents and reports which were added, removed or modified.
+// Elements are matched by their path, so the diff is coarse: an element inserted before its siblings
+// with the same tag shifts their paths and shows up as a series of modifications and an addition.
+// An element is modified if its attributes, ignoring their order, or its own text differ. The own text is
+// the trimmed text of its direct text children, so a change deep in the tree is only reported for the
+// element that contains the text. Removed and added subtrees are reported once, by their root.
+//
+// The changes of old elements come first in document order of a, followed by the additions in document order of b.
+func Diff(a, b *Node) []Change {
+	oldPaths, oldElements := elementPaths(a.backing)
+	newPaths, newElements := elementPaths(b.backing)
+	res := make([]Change, 0)
+	removed := make(map[string]bool)
+	for _, path := range oldPaths {
+		o := oldElements[path]
+		n, ok := newElements[path]
+		switch {
+		case !ok:
+			removed[path] = true
+			if !removed[parentPath(path)] {
+				res = append(res, Change{Kind: ElementRemoved, Path: path, Old: newNode(o)})
+			}
+		case !sameElement(o, n):
+			res = append(res, Change{Kind: ElementModified, Path: path, Old: newNode(o), New: newNode(n)})
+		}
+	}
+	added := make(map[string]bool)
+	for _, path := range newPaths {
+		if _, ok := oldElements[path]; ok {
+			continue
+		}
+		added[path] = true
+		if !added[parentPath(path)] {
+			res = append(res, Change{Kind: ElementAdded, Path: path, New: newNode(newElements[path])})
+		}
+	}
+	return res
+}
+
+// elementPaths returns the paths of all descendant elements of node in document order along with the elements.
+func elementPaths(node *html.Node) ([]string, map[string]*html.Node) {
+	paths := make([]string, 0)
+	elements := make(map[string]*html.Node)
+	var visit func(n *html.Node, prefix string)
+	visit = func(n *html.Node, prefix string) {
+		counts := make(map[string]int)
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			counts[c.Data]++
+			path := fmt.Sprintf("%s/%s[%d]", prefix, c.Data, counts[c.Data])
+			paths = append(paths, path)
+			elements[path] = c
+			visit(c, path)
+		}
+	}
+	visit(node, "")
+	return paths, elements
+}
+
+func parentPath(path string) string {
+	return path[:strings.LastIndexByte(path, '/')]
+}
+
+// sameElement returns true if the elements have the same attributes, ignoring their order, and the same own text.
+func sameElement(a, b *html.Node) bool {
+	if len(a.Attr) != len(b.Attr) || ownText(a) != ownText(b) {
+		return false
+	}
+	attrs := func(n *html.Node) []string {
+		res := make([]string, 0, len(n.Attr))
+		for _, attr := range n.Attr {
+			res = append(res, attr.Namespace+":"+attr.Key+"="+attr.Val)
+		}
+		sort.Strings(res)
+		return res
+	}
+	x, y := attrs(a), attrs(b)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ownText joins the trimmed, non-empty text of the direct text children with a single space.
+func ownText(node *html.Node) string {
+	runs := make([]string, 0)
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			if t := trim(c.Data); len(t) > 0 {
+				runs = append(runs, t)
+			}
+		}
+	}
+	return strings.Join(runs, " ")
+}
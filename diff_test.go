@@ -0,0 +1,57 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{"equal", `<div id="a"><p>x</p></div>`, `<div id="a"> <p> x </p></div>`, nil},
+		{"own text", `<div><p>x <b>y</b></p></div>`, `<div><p>z <b>y</b></p></div>`,
+			[]string{"modified /html[1]/body[1]/div[1]/p[1]"}},
+		{"nested text", `<div><p>x <b>y</b></p></div>`, `<div><p>x <b>z</b></p></div>`,
+			[]string{"modified /html[1]/body[1]/div[1]/p[1]/b[1]"}},
+		{"attribute", `<div><a href="/a" id="x">a</a></div>`, `<div><a href="/b" id="x">a</a></div>`,
+			[]string{"modified /html[1]/body[1]/div[1]/a[1]"}},
+		{"attribute order", `<div><a href="/a" id="x" class="c">a</a></div>`, `<div><a class="c" id="x" href="/a">a</a></div>`, nil},
+		{"added subtree", `<div><p>a</p></div>`, `<div><p>a</p><section><h2>b</h2><p>c</p></section></div>`,
+			[]string{"added /html[1]/body[1]/div[1]/section[1]"}},
+		{"removed subtree", `<div><ul><li>a</li><li>b</li></ul><p>c</p></div>`, `<div><p>c</p></div>`,
+			[]string{"removed /html[1]/body[1]/div[1]/ul[1]"}},
+		{"path shift", `<div><p>a</p><p>b</p></div>`, `<div><p>new</p><p>a</p><p>b</p></div>`, []string{
+			"modified /html[1]/body[1]/div[1]/p[1]",
+			"modified /html[1]/body[1]/div[1]/p[2]",
+			"added /html[1]/body[1]/div[1]/p[3]",
+		}},
+	}
+	for _, tt := range tests {
+		a, err := Parse(strings.NewReader(tt.a))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := Parse(strings.NewReader(tt.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes := Diff(a, b)
+		got := make([]string, 0, len(changes))
+		for _, c := range changes {
+			got = append(got, c.String())
+			if (c.Old == nil) != (c.Kind == ElementAdded) || (c.New == nil) != (c.Kind == ElementRemoved) {
+				t.Errorf("%s: %s has Old %v and New %v", tt.name, c, c.Old, c.New)
+			}
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: Diff = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
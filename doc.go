@@ -0,0 +1,33 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package soup is a tiny library for extracting data from HTML.
+//
+// Parse a document and query it with the Node methods, a Selector or a CSS selector string:
+//
+//	p, err := soup.Parse(r)
+//	if err != nil {
+//		return err
+//	}
+//	for _, product := range p.AllWithClassNameR("product") {
+//		fmt.Println(product.FirstWithTag("a").Attr("href"))
+//	}
+//
+// Most Node methods have a package level counterpart of the same name working on *html.Node,
+// for code that uses golang.org/x/net/html directly.
+//
+// # Mutating the tree
+//
+// Queries returning a slice collect all matches before they return, so the slice is a snapshot
+// that isn't affected by later changes to the tree. It is safe to remove, move or wrap the nodes
+// of a result while iterating over it:
+//
+//	gallery := p.FirstWithIdR("gallery")
+//	for _, img := range p.AllWithTagR("img") {
+//		gallery.Append(img)
+//	}
+//
+// In contrast, walking the tree by hand while changing it is not safe, because a removed node
+// no longer has siblings to continue with.
+package soup
@@ -0,0 +1,57 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+)
+
+// This file contains aliases with the semantics of their DOM counterparts for those porting code from the browser.
+
+// GetElementById returns the first descendant in document order with the given id.
+func (n *Node) GetElementById(id string) *Node {
+	res := selectAll(n.backing, func(c *html.Node) bool {
+		return c.Type == html.ElementNode && Attr(c, "id") == id
+	}, true, 1)
+	if len(res) > 0 {
+		return newNode(res[0])
+	}
+	return nil
+}
+
+// GetElementsByClassName returns all descendants that have all of the given space separated classes.
+func (n *Node) GetElementsByClassName(classNames string) []*Node {
+	classes := strings.Fields(classNames)
+	if len(classes) == 0 {
+		return []*Node{}
+	}
+	return newNodes(selectAll(n.backing, func(c *html.Node) bool {
+		for _, class := range classes {
+			if !HasClass(c, class) {
+				return false
+			}
+		}
+		return true
+	}, true, 0))
+}
+
+// GetElementsByTagName returns all descendants with the given tag, compared case-insensitively.
+// The tag "*" matches all elements.
+func (n *Node) GetElementsByTagName(tag string) []*Node {
+	return newNodes(selectAll(n.backing, func(c *html.Node) bool {
+		return c.Type == html.ElementNode && (tag == "*" || strings.EqualFold(c.Data, tag))
+	}, true, 0))
+}
+
+// QuerySelector is an alias for FindOne.
+func (n *Node) QuerySelector(selector string) (*Node, error) {
+	return n.FindOne(selector)
+}
+
+// QuerySelectorAll is an alias for Find.
+func (n *Node) QuerySelectorAll(selector string) ([]*Node, error) {
+	return n.Find(selector)
+}
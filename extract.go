@@ -0,0 +1,549 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// lazyImageAttrs are attributes commonly used by lazy loading scripts instead of src.
+var lazyImageAttrs = []string{"data-src", "data-lazy-src", "data-original"}
+
+// lazySrcsetAttrs are attributes commonly used by lazy loading scripts instead of srcset.
+var lazySrcsetAttrs = []string{"data-srcset", "data-lazy-srcset"}
+
+// Canonical returns the canonical URL of the document.
+// See Canonical for details.
+func (n *Node) Canonical() string {
+	return Canonical(n.backing)
+}
+
+// DefinitionList returns the terms and descriptions of a <dl> element.
+// See DefinitionList for details.
+func (n *Node) DefinitionList() map[string]string {
+	return DefinitionList(n.backing)
+}
+
+// DistinctAttrValues returns the distinct values of the attribute within the node's subtree.
+// See DistinctAttrValues for details.
+func (n *Node) DistinctAttrValues(key string) []string {
+	return DistinctAttrValues(n.backing, key)
+}
+
+// FollowableLinks returns the targets of all descendant links that may be followed by a crawler.
+// See FollowableLinks for details.
+func (n *Node) FollowableLinks(base *url.URL) []string {
+	return FollowableLinks(n.backing, base)
+}
+
+// JSONLD returns the objects of all descendant JSON-LD blocks.
+// See JSONLD for details.
+func (n *Node) JSONLD() ([]map[string]any, error) {
+	return JSONLD(n.backing)
+}
+
+// Images returns the sources of all descendant <img> elements.
+// See Images for details.
+func (n *Node) Images(base *url.URL) []string {
+	return Images(n.backing, base)
+}
+
+// DefinitionList returns the terms and descriptions of a <dl> element, keyed by the text of each <dt>.
+// The text of multiple <dd> elements following a term is joined with ", ". Consecutive terms share
+// the descriptions that follow them. <div> elements grouping terms and descriptions are supported.
+func DefinitionList(node *html.Node) map[string]string {
+	res := make(map[string]string)
+	terms := make([]string, 0)
+	descriptions := false
+	var visit func(*html.Node)
+	visit = func(parent *html.Node) {
+		for c := parent.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "div":
+				visit(c)
+			case "dt":
+				if descriptions {
+					terms = terms[:0]
+					descriptions = false
+				}
+				term := TextContentR(c)
+				terms = append(terms, term)
+				res[term] = ""
+			case "dd":
+				descriptions = true
+				desc := TextContentR(c)
+				for _, term := range terms {
+					if len(res[term]) > 0 {
+						res[term] += ", " + desc
+					} else {
+						res[term] = desc
+					}
+				}
+			}
+		}
+	}
+	visit(node)
+	return res
+}
+
+// DistinctAttrValues returns the distinct, non-empty values of the attribute within the node's subtree,
+// including the node itself, in the order they first appear. The class attribute is split into the individual classes.
+func DistinctAttrValues(node *html.Node, key string) []string {
+	res := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(n *html.Node) {
+		if !hasAttr(n, key) {
+			return
+		}
+		values := []string{Attr(n, key)}
+		if key == "class" {
+			values = strings.Fields(values[0])
+		}
+		for _, v := range values {
+			if len(v) > 0 && !seen[v] {
+				seen[v] = true
+				res = append(res, v)
+			}
+		}
+	}
+	add(node)
+	walk(node, func(c *html.Node) bool {
+		if c.Type != html.ElementNode {
+			return false
+		}
+		add(c)
+		return true
+	})
+	return res
+}
+
+// FollowableLinks returns the href of all descendant <a> elements in document order without duplicates.
+// Links with a rel attribute containing nofollow, empty links and javascript: links are skipped.
+// The links are resolved against base unless base is nil.
+func FollowableLinks(node *html.Node, base *url.URL) []string {
+	res := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, a := range AllWithTagR(node, "a") {
+		href := strings.TrimSpace(Attr(a, "href"))
+		if len(href) == 0 || hasToken(Attr(a, "rel"), "nofollow") || hasPrefixFold(href, "javascript:") {
+			continue
+		}
+		u, ok := resolveURL(base, href)
+		if !ok || seen[u] {
+			continue
+		}
+		seen[u] = true
+		res = append(res, u)
+	}
+	return res
+}
+
+// Images returns the sources of all descendant <img> elements in document order without duplicates.
+// Besides src, the candidates of srcset and common lazy loading attributes like data-src are included.
+// The sources are resolved against base unless base is nil.
+func Images(node *html.Node, base *url.URL) []string {
+	res := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(ref string) {
+		ref = strings.TrimSpace(ref)
+		if len(ref) == 0 {
+			return
+		}
+		u, ok := resolveURL(base, ref)
+		if !ok || seen[u] {
+			return
+		}
+		seen[u] = true
+		res = append(res, u)
+	}
+	for _, img := range AllWithTagR(node, "img") {
+		add(Attr(img, "src"))
+		for _, attr := range lazyImageAttrs {
+			add(Attr(img, attr))
+		}
+		for _, ref := range parseSrcset(Attr(img, "srcset")) {
+			add(ref)
+		}
+		for _, attr := range lazySrcsetAttrs {
+			for _, ref := range parseSrcset(Attr(img, attr)) {
+				add(ref)
+			}
+		}
+	}
+	return res
+}
+
+// JSONLD returns the objects of all descendant <script type="application/ld+json"> blocks in document order.
+// Blocks containing an array contribute each object of the array.
+// Malformed blocks are skipped. The result contains all valid blocks, even if the returned error,
+// which describes the skipped blocks, is not nil.
+func JSONLD(node *html.Node) ([]map[string]any, error) {
+	res := make([]map[string]any, 0)
+	errs := make([]error, 0)
+	block := 0
+	for _, script := range AllWithTagR(node, "script") {
+		if !strings.EqualFold(strings.TrimSpace(Attr(script, "type")), "application/ld+json") {
+			continue
+		}
+		block++
+		data := []byte(ScriptData(script))
+		var obj map[string]any
+		if err := json.Unmarshal(data, &obj); err == nil {
+			res = append(res, obj)
+			continue
+		}
+		var list []map[string]any
+		if err := json.Unmarshal(data, &list); err != nil {
+			errs = append(errs, fmt.Errorf("soup: JSON-LD block %d: %w", block, err))
+			continue
+		}
+		res = append(res, list...)
+	}
+	return res, errors.Join(errs...)
+}
+
+// StreamLinks calls fn with the href of each <a> element in r as it is encountered, without building a tree.
+// Memory use doesn't depend on the size of the document, which makes StreamLinks suitable for huge pages.
+// Entities in the href are decoded, but the href is neither trimmed nor resolved. Anchors without href are skipped.
+// StreamLinks returns nil once the end of r is reached or the first read error otherwise.
+func StreamLinks(r io.Reader, fn func(href string)) error {
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return err
+			}
+			return nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, more := z.TagName()
+			if string(name) != "a" {
+				continue
+			}
+			for more {
+				var key, val []byte
+				key, val, more = z.TagAttr()
+				if string(key) == "href" {
+					fn(string(val))
+					break
+				}
+			}
+		}
+	}
+}
+
+// Lang returns the language of the node.
+// See Lang for details.
+func (n *Node) Lang() string {
+	return Lang(n.backing)
+}
+
+// MetaRobots returns the content of the <meta name="robots"> element.
+// See MetaRobots for details.
+func (n *Node) MetaRobots() string {
+	return MetaRobots(n.backing)
+}
+
+// Canonical returns the href of the first descendant <link rel="canonical"> element or an empty string if there is none.
+// If the document declares a <base href>, the URL is resolved against it.
+func Canonical(node *html.Node) string {
+	link := selectFirst(node, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "link" && hasToken(Attr(n, "rel"), "canonical")
+	}, true)
+	if link == nil {
+		return ""
+	}
+	href := strings.TrimSpace(Attr(link, "href"))
+	if base := FirstWithTagR(node, "base"); base != nil && len(href) > 0 {
+		if b, err := url.Parse(strings.TrimSpace(Attr(base, "href"))); err == nil && b.IsAbs() {
+			if u, ok := resolveURL(b, href); ok {
+				return u
+			}
+		}
+	}
+	return href
+}
+
+// Lang returns the lang attribute of the node or its nearest ancestor that has one, so that the
+// language is inherited just like in the browser. For a document, the lang of the <html> element is returned.
+func Lang(node *html.Node) string {
+	if node.Type == html.DocumentNode {
+		if root := FirstWithTag(node, "html"); root != nil {
+			node = root
+		}
+	}
+	res := closest(node, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && hasAttr(n, "lang")
+	})
+	if res == nil {
+		return ""
+	}
+	return strings.TrimSpace(Attr(res, "lang"))
+}
+
+// MetaRobots returns the content of the first descendant <meta name="robots"> element
+// or an empty string if there is none.
+func MetaRobots(node *html.Node) string {
+	meta := selectFirst(node, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "meta" && strings.EqualFold(strings.TrimSpace(Attr(n, "name")), "robots")
+	}, true)
+	if meta == nil {
+		return ""
+	}
+	return strings.TrimSpace(Attr(meta, "content"))
+}
+
+// OpenGraph holds the basic OpenGraph metadata of a document.
+type OpenGraph struct {
+	Title       string
+	Type        string
+	URL         string
+	Description string
+	SiteName    string
+	// Images are the og:image values in document order without duplicates.
+	Images []string
+}
+
+// OpenGraph returns the OpenGraph metadata of the node's subtree.
+// See OpenGraph for details.
+func (n *Node) OpenGraph() OpenGraph {
+	return ExtractOpenGraph(n.backing)
+}
+
+// ExtractOpenGraph returns the OpenGraph metadata from the og:* <meta> elements of the node's subtree.
+// For each field but Images, the first non-empty value wins. Besides the property attribute
+// of the specification, the name attribute is accepted too, because many pages use it instead.
+// og:image:url is treated as an alias of og:image. Values are trimmed but not resolved.
+func ExtractOpenGraph(node *html.Node) OpenGraph {
+	var res OpenGraph
+	res.Images = make([]string, 0)
+	seen := make(map[string]bool)
+	set := func(field *string, v string) {
+		if len(*field) == 0 {
+			*field = v
+		}
+	}
+	walk(node, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		if n.Data != "meta" {
+			return true
+		}
+		property := Attr(n, "property")
+		if len(property) == 0 {
+			property = Attr(n, "name")
+		}
+		v := strings.TrimSpace(Attr(n, "content"))
+		if len(v) == 0 {
+			return false
+		}
+		switch strings.ToLower(strings.TrimSpace(property)) {
+		case "og:title":
+			set(&res.Title, v)
+		case "og:type":
+			set(&res.Type, v)
+		case "og:url":
+			set(&res.URL, v)
+		case "og:description":
+			set(&res.Description, v)
+		case "og:site_name":
+			set(&res.SiteName, v)
+		case "og:image", "og:image:url":
+			if !seen[v] {
+				seen[v] = true
+				res.Images = append(res.Images, v)
+			}
+		}
+		return false
+	})
+	return res
+}
+
+// ToMap runs each selector against the node and maps its key to the text of the first match.
+// See ToMap for details.
+func (n *Node) ToMap(fields map[string]Selector) map[string]string {
+	return ToMap(n.backing, fields)
+}
+
+// ToMap runs each selector against the node and maps its key to the text of the first match
+// as returned by TextContentR. Keys whose selector doesn't match map to an empty string.
+func ToMap(node *html.Node, fields map[string]Selector) map[string]string {
+	res := make(map[string]string, len(fields))
+	for key, selector := range fields {
+		if m := SelectFirst(node, selector); m != nil {
+			res[key] = TextContentR(m)
+		} else {
+			res[key] = ""
+		}
+	}
+	return res
+}
+
+// ExtractAll selects all items and extracts a record from each of them with ToMap.
+func (n *Node) ExtractAll(itemSelector Selector, fields map[string]Selector) []map[string]string {
+	return ExtractAll(n.backing, itemSelector, fields)
+}
+
+// ExtractAll selects all items and extracts a record from each of them with ToMap.
+// The records are in the order SelectAll returns the items.
+func ExtractAll(node *html.Node, itemSelector Selector, fields map[string]Selector) []map[string]string {
+	items := SelectAll(node, itemSelector)
+	res := make([]map[string]string, 0, len(items))
+	for _, item := range items {
+		res = append(res, ToMap(item, fields))
+	}
+	return res
+}
+
+// SelectedOption returns the selected <option> of a <select> element.
+// See SelectedOption for details.
+func (n *Node) SelectedOption() *Node {
+	res := SelectedOption(n.backing)
+	if res != nil {
+		return newNode(res)
+	}
+	return nil
+}
+
+// SelectedOptions returns all selected <option> elements of a <select> element.
+// See SelectedOptions for details.
+func (n *Node) SelectedOptions() []*Node {
+	return newNodes(SelectedOptions(n.backing))
+}
+
+// SelectedOption returns the selected <option> of a <select> element, including options within an <optgroup>,
+// or nil if the select has no options. As in the browser, the last option with the selected attribute wins
+// and the first option that isn't disabled is selected if none has the attribute.
+// For multi-selects, use SelectedOptions.
+func SelectedOption(node *html.Node) *html.Node {
+	options := selectAll(node, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "option"
+	}, true, 0)
+	for i := len(options) - 1; i >= 0; i-- {
+		if hasAttr(options[i], "selected") {
+			return options[i]
+		}
+	}
+	for _, o := range options {
+		if !hasAttr(o, "disabled") {
+			return o
+		}
+	}
+	return nil
+}
+
+// SelectedOptions returns the selected <option> elements of a <select> element in document order.
+// For a <select multiple>, these are all options with the selected attribute. Otherwise, the result contains
+// the option returned by SelectedOption, if any.
+func SelectedOptions(node *html.Node) []*html.Node {
+	if !hasAttr(node, "multiple") {
+		if o := SelectedOption(node); o != nil {
+			return []*html.Node{o}
+		}
+		return make([]*html.Node, 0)
+	}
+	return selectAll(node, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "option" && hasAttr(n, "selected")
+	}, true, 0)
+}
+
+// TagHistogram counts the elements of the node's subtree by tag name.
+// See TagHistogram for details.
+func (n *Node) TagHistogram() map[string]int {
+	return TagHistogram(n.backing)
+}
+
+// TagHistogram counts the elements of the node's subtree, including the node itself, by tag name.
+// The subtree is traversed once.
+func TagHistogram(node *html.Node) map[string]int {
+	res := make(map[string]int)
+	if node.Type == html.ElementNode {
+		res[node.Data]++
+	}
+	walk(node, func(c *html.Node) bool {
+		if c.Type != html.ElementNode {
+			return false
+		}
+		res[c.Data]++
+		return true
+	})
+	return res
+}
+
+// parseSrcset returns the candidate URLs of a srcset attribute, dropping the width and density descriptors.
+// As in the HTML specification, a URL ends at whitespace rather than at a comma, so data: URLs containing
+// commas are kept intact. Commas directly after a URL and outside of parentheses in descriptors separate candidates.
+func parseSrcset(srcset string) []string {
+	res := make([]string, 0)
+	isSpace := func(ch byte) bool {
+		return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\f' || ch == '\r'
+	}
+	for i := 0; i < len(srcset); {
+		for i < len(srcset) && (isSpace(srcset[i]) || srcset[i] == ',') {
+			i++
+		}
+		start := i
+		for i < len(srcset) && !isSpace(srcset[i]) {
+			i++
+		}
+		u := srcset[start:i]
+		trimmed := strings.TrimRight(u, ",")
+		if len(trimmed) > 0 {
+			res = append(res, trimmed)
+		}
+		if len(trimmed) < len(u) {
+			// a URL ending with a comma has no descriptors
+			continue
+		}
+		depth := 0
+		for ; i < len(srcset); i++ {
+			ch := srcset[i]
+			if ch == '(' {
+				depth++
+			} else if ch == ')' && depth > 0 {
+				depth--
+			} else if ch == ',' && depth == 0 {
+				break
+			}
+		}
+	}
+	return res
+}
+
+// resolveURL resolves ref against base. If base is nil, ref is returned as is.
+func resolveURL(base *url.URL, ref string) (string, bool) {
+	if base == nil {
+		return ref, true
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// hasToken returns true if the space separated list contains the token, ignoring case.
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
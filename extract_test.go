@@ -0,0 +1,31 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSrcset(t *testing.T) {
+	tests := []struct {
+		srcset string
+		want   []string
+	}{
+		{"a.jpg", []string{"a.jpg"}},
+		{"a.jpg 1x, b.jpg 2x", []string{"a.jpg", "b.jpg"}},
+		{"  a.jpg  480w ,b.jpg 800w  ", []string{"a.jpg", "b.jpg"}},
+		{"a.jpg, b.jpg,", []string{"a.jpg", "b.jpg"}},
+		{"data:image/png;base64,AA 1x, b.jpg 2x", []string{"data:image/png;base64,AA", "b.jpg"}},
+		{"a.jpg (foo, bar) 1x, b.jpg", []string{"a.jpg", "b.jpg"}},
+		{"", []string{}},
+		{" , ", []string{}},
+	}
+	for _, tt := range tests {
+		if got := parseSrcset(tt.srcset); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("parseSrcset(%q) = %q, want %q", tt.srcset, got, tt.want)
+		}
+	}
+}
@@ -3,3 +3,5 @@ module github.com/cfichtmueller/soup
 go 1.20
 
 require golang.org/x/net v0.12.0
+
+require golang.org/x/text v0.11.0 // indirect
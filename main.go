@@ -5,23 +5,77 @@
 package soup
 
 import (
+	"errors"
 	"fmt"
 	"golang.org/x/net/html"
 	"io"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
+// ErrAmbiguous is returned by SelectFirstStrict if the selector matches more than one node.
+var ErrAmbiguous = errors.New("soup: selector matches more than one node")
+
 type Selector struct {
 	// Selects an element with a given id. Takes precedence over ClassName
 	Id string
+	// Compare Id case-insensitively. Ids are case-sensitive per spec, but legacy pages often mix case.
+	CaseInsensitiveId bool
 	// Selects an element with a given class. Takes precedence over Tag
 	ClassName string
-	// Selects an element with a given tag
+	// Selects an element with a given tag. The universal tag "*" selects any element, just like
+	// a selector without Id, ClassName, Tag and TextMatch does.
 	Tag string
+	// Selects an element whose text content matches the expression. The text content is the
+	// full descendant text as returned by TextContentR. Applies in addition to Id, ClassName and Tag.
+	TextMatch *regexp.Regexp
+	// Selects an element that has a descendant matching the selector, like :has() in CSS.
+	// All descendants are searched, no matter the Recursive flag of Has. Applies in addition to the other fields.
+	Has *Selector
+	// Caps the number of nodes returned by SelectAll. The search stops once the limit is reached.
+	// A limit of 0 means unlimited.
+	Limit int
+	// Don't search the descendants of a match in a recursive search, so that only the outermost
+	// of nested matches are returned by SelectAll.
+	OutermostOnly bool
 	// Perform a recursive search. That is, include the node's children in the search.
 	Recursive bool
 }
 
+// matches returns true if the node itself matches the selector. Recursive is ignored.
+func (s Selector) matches(node *html.Node) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+	switch {
+	case len(s.Id) > 0:
+		if !s.matchesId(Attr(node, "id")) {
+			return false
+		}
+	case len(s.ClassName) > 0:
+		if !HasClass(node, s.ClassName) {
+			return false
+		}
+	case len(s.Tag) > 0:
+		if s.Tag != "*" && node.Data != s.Tag {
+			return false
+		}
+	}
+	if s.Has != nil && !Has(node, *s.Has) {
+		return false
+	}
+	return s.TextMatch == nil || s.TextMatch.MatchString(TextContentR(node))
+}
+
+func (s Selector) matchesId(id string) bool {
+	if s.CaseInsensitiveId {
+		return strings.EqualFold(id, s.Id)
+	}
+	return id == s.Id
+}
+
 type Node struct {
 	backing *html.Node
 }
@@ -48,6 +102,17 @@ func (n *Node) AllWithClassNameR(className string) []*Node {
 	return newNodes(AllWithClassNameR(n.backing, className))
 }
 
+// AllWithAttrContains returns all descendants whose attribute contains substr in document order.
+// See AllWithAttrContains for details.
+func (n *Node) AllWithAttrContains(key, substr string) []*Node {
+	return newNodes(AllWithAttrContains(n.backing, key, substr))
+}
+
+// AllWithIds returns all descendants with one of the given ids in document order.
+func (n *Node) AllWithIds(ids ...string) []*Node {
+	return newNodes(AllWithIds(n.backing, ids...))
+}
+
 // AllWithTag returns all child nodes with the given tag.
 func (n *Node) AllWithTag(tagName string) []*Node {
 	return newNodes(AllWithTag(n.backing, tagName))
@@ -58,11 +123,49 @@ func (n *Node) AllWithTagR(tagName string) []*Node {
 	return newNodes(AllWithTagR(n.backing, tagName))
 }
 
+// AllWithTagAndClass returns all child nodes with the given tag and class.
+func (n *Node) AllWithTagAndClass(tagName, className string) []*Node {
+	return newNodes(AllWithTagAndClass(n.backing, tagName, className))
+}
+
+// AllWithTagAndClassR is the recursive variant of AllWithTagAndClass.
+func (n *Node) AllWithTagAndClassR(tagName, className string) []*Node {
+	return newNodes(AllWithTagAndClassR(n.backing, tagName, className))
+}
+
 // Attr returns the attribute value or an empty string if the attribute isn't found.
 func (n *Node) Attr(attr string) string {
 	return Attr(n.backing, attr)
 }
 
+// AttrBool interprets the attribute as a boolean attribute.
+// See AttrBool for details.
+func (n *Node) AttrBool(key string) bool {
+	return AttrBool(n.backing, key)
+}
+
+// AttrFold is like Attr but compares attribute keys case-insensitively.
+func (n *Node) AttrFold(attr string) string {
+	return AttrFold(n.backing, attr)
+}
+
+// AttrsWithPrefix returns all attributes whose key starts with the given prefix.
+func (n *Node) AttrsWithPrefix(prefix string) map[string]string {
+	return AttrsWithPrefix(n.backing, prefix)
+}
+
+// DataInt parses the data attribute with the given name as an integer.
+// See DataInt for details.
+func (n *Node) DataInt(key string) (int, bool) {
+	return DataInt(n.backing, key)
+}
+
+// FirstChildText returns the trimmed text of the first text child of the node.
+// See FirstChildText for details.
+func (n *Node) FirstChildText() string {
+	return FirstChildText(n.backing)
+}
+
 // FirstWithClassName returns the first child with the given class.
 func (n *Node) FirstWithClassName(className string) *Node {
 	res := FirstWithClassName(n.backing, className)
@@ -117,11 +220,47 @@ func (n *Node) FirstWithTagR(tag string) *Node {
 	return nil
 }
 
+// FirstWithTagAndClass returns the first child node with the given tag and class.
+func (n *Node) FirstWithTagAndClass(tag, className string) *Node {
+	res := FirstWithTagAndClass(n.backing, tag, className)
+	if res != nil {
+		return newNode(res)
+	}
+	return nil
+}
+
+// FirstWithTagAndClassR is the recursive variant of FirstWithTagAndClass.
+func (n *Node) FirstWithTagAndClassR(tag, className string) *Node {
+	res := FirstWithTagAndClassR(n.backing, tag, className)
+	if res != nil {
+		return newNode(res)
+	}
+	return nil
+}
+
+// Has returns true if the node has a descendant matching the selector.
+// See Has for details.
+func (n *Node) Has(selector Selector) bool {
+	return Has(n.backing, selector)
+}
+
 // HasClass returns true if the node has the given class
 func (n *Node) HasClass(className string) bool {
 	return HasClass(n.backing, className)
 }
 
+// Matches returns true if the node itself matches the selector.
+// See Matches for details.
+func (n *Node) Matches(selector Selector) bool {
+	return Matches(n.backing, selector)
+}
+
+// MatchesAny returns true if the node itself matches at least one of the selectors.
+// See MatchesAny for details.
+func (n *Node) MatchesAny(selectors ...Selector) bool {
+	return MatchesAny(n.backing, selectors...)
+}
+
 // SelectAll selects all child node that match the given Selector.
 func (n *Node) SelectAll(selector Selector) []*Node {
 	res := SelectAll(n.backing, selector)
@@ -131,6 +270,18 @@ func (n *Node) SelectAll(selector Selector) []*Node {
 	return nil
 }
 
+// SelectAllExcept is like SelectAll but skips matches inside an element matching exclude.
+// See SelectAllExcept for details.
+func (n *Node) SelectAllExcept(selector, exclude Selector) []*Node {
+	return newNodes(SelectAllExcept(n.backing, selector, exclude))
+}
+
+// SelectAny selects all child nodes that match at least one of the selectors.
+// See SelectAny for details.
+func (n *Node) SelectAny(selectors ...Selector) []*Node {
+	return newNodes(SelectAny(n.backing, selectors...))
+}
+
 // SelectFirst selects the first child node that matches the given selector.
 func (n *Node) SelectFirst(selector Selector) *Node {
 	res := SelectFirst(n.backing, selector)
@@ -140,11 +291,35 @@ func (n *Node) SelectFirst(selector Selector) *Node {
 	return nil
 }
 
+// SelectFirstStrict is like SelectFirst but returns ErrAmbiguous if the selector matches more than one node.
+func (n *Node) SelectFirstStrict(selector Selector) (*Node, error) {
+	res, err := SelectFirstStrict(n.backing, selector)
+	if res != nil {
+		return newNode(res), nil
+	}
+	return nil, err
+}
+
+// SelectSorted is like SelectAll but sorts the matches with less.
+// See SelectSorted for details.
+func (n *Node) SelectSorted(selector Selector, less func(a, b *Node) bool) []*Node {
+	res := newNodes(SelectAll(n.backing, selector))
+	sort.SliceStable(res, func(i, j int) bool { return less(res[i], res[j]) })
+	return res
+}
+
+// Style returns the declarations of the inline style attribute.
+// See Style for details.
+func (n *Node) Style() map[string]string {
+	return Style(n.backing)
+}
+
 func (n *Node) String() string {
 	return fmt.Sprintf("%v", n.backing.Data)
 }
 
-// TextContent returns the text content of the node
+// TextContent returns the text of all descendant text nodes.
+// See TextContent for details.
 func (n *Node) TextContent() string {
 	return TextContent(n.backing)
 }
@@ -158,34 +333,151 @@ func Parse(r io.Reader) (*Node, error) {
 	return newNode(root), nil
 }
 
+// Matches returns true if the node itself matches the selector. Recursive, Limit and OutermostOnly are ignored.
+// Only elements match.
+func Matches(node *html.Node, selector Selector) bool {
+	return selector.matches(node)
+}
+
+// MatchesAny returns true if the node itself matches at least one of the selectors, which makes it
+// the single node counterpart of SelectAny. It returns false if no selectors are given.
+func MatchesAny(node *html.Node, selectors ...Selector) bool {
+	return matchesAny(node, selectors)
+}
+
 // SelectAll selects all child node that match the given Selector
 func SelectAll(node *html.Node, selector Selector) []*html.Node {
-	if len(selector.Id) > 0 {
-		node := firstWithId(node, selector.Id, selector.Recursive)
-		if node == nil {
-			return []*html.Node{node}
+	res := make([]*html.Node, 0)
+	collect(node, selector.matches, selector.Recursive, selector.OutermostOnly, selector.Limit, &res)
+	return res
+}
+
+// SelectAllExcept is like SelectAll but skips matches that live inside an element matching exclude,
+// e.g. all links except those in the footer. Only the ancestors of a match below node are checked against exclude,
+// so a match that matches exclude itself is kept. The limit of the selector applies to the remaining matches.
+func SelectAllExcept(node *html.Node, selector, exclude Selector) []*html.Node {
+	limit := selector.Limit
+	selector.Limit = 0
+	res := make([]*html.Node, 0)
+	for _, m := range SelectAll(node, selector) {
+		if limit > 0 && len(res) == limit {
+			break
+		}
+		excluded := false
+		for p := m.Parent; p != nil && p != node; p = p.Parent {
+			if exclude.matches(p) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			res = append(res, m)
 		}
-		return []*html.Node{}
 	}
-	if len(selector.ClassName) > 0 {
-		return allWithClassName(node, selector.ClassName, selector.Recursive)
+	return res
+}
+
+// SelectAny selects all child nodes that match at least one of the selectors.
+// The result is in document order and contains every node once, no matter how many selectors match it.
+// Descendants are only matched against recursive selectors. Limit is ignored.
+func SelectAny(node *html.Node, selectors ...Selector) []*html.Node {
+	recursive := make([]Selector, 0, len(selectors))
+	for _, s := range selectors {
+		if s.Recursive {
+			recursive = append(recursive, s)
+		}
 	}
-	if len(selector.Tag) > 0 {
-		return allWithTag(node, selector.Tag, selector.Recursive)
+	res := make([]*html.Node, 0)
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if matchesAny(c, selectors) {
+			res = append(res, c)
+		}
+		if len(recursive) > 0 {
+			res = append(res, selectAll(c, func(n *html.Node) bool {
+				return matchesAny(n, recursive)
+			}, true, 0)...)
+		}
 	}
-	return nil
+	return res
+}
+
+func matchesAny(node *html.Node, selectors []Selector) bool {
+	for _, s := range selectors {
+		if s.matches(node) {
+			return true
+		}
+	}
+	return false
 }
 
 // SelectFirst selects the first child node that matches the given selector
 func SelectFirst(node *html.Node, selector Selector) *html.Node {
-	if len(selector.Id) > 0 {
-		return firstWithId(node, selector.Id, selector.Recursive)
+	return selectFirst(node, selector.matches, selector.Recursive)
+}
+
+// SelectFirstStrict is like SelectFirst but returns ErrAmbiguous if the selector matches more than one node.
+// This helps detecting selectors that became non-unique after the page changed.
+func SelectFirstStrict(node *html.Node, selector Selector) (*html.Node, error) {
+	selector.Limit = 2
+	res := SelectAll(node, selector)
+	switch len(res) {
+	case 0:
+		return nil, nil
+	case 1:
+		return res[0], nil
+	}
+	return nil, ErrAmbiguous
+}
+
+// SelectSorted is like SelectAll but sorts the matches with less.
+// The sort is stable, so matches that are equal according to less stay in document order.
+func SelectSorted(node *html.Node, selector Selector, less func(a, b *html.Node) bool) []*html.Node {
+	res := SelectAll(node, selector)
+	sort.SliceStable(res, func(i, j int) bool { return less(res[i], res[j]) })
+	return res
+}
+
+// selectAll returns all children matching match in document order, including all descendants if recursive.
+// At most limit nodes are returned if limit is greater than 0.
+func selectAll(node *html.Node, match func(*html.Node) bool, recursive bool, limit int) []*html.Node {
+	res := make([]*html.Node, 0)
+	collect(node, match, recursive, false, limit, &res)
+	return res
+}
+
+// collect appends the matching children of node to res. If outermost, the descendants of matches aren't searched.
+// It returns false once limit is reached.
+func collect(node *html.Node, match func(*html.Node) bool, recursive, outermost bool, limit int, res *[]*html.Node) bool {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		matched := match(c)
+		if matched {
+			*res = append(*res, c)
+			if limit > 0 && len(*res) >= limit {
+				return false
+			}
+		}
+		if recursive && !(outermost && matched) && !collect(c, match, true, outermost, limit, res) {
+			return false
+		}
 	}
-	if len(selector.ClassName) > 0 {
-		return firstWithClassName(node, selector.ClassName, selector.Recursive)
+	return true
+}
+
+// selectFirst returns the first child matching match. If recursive, the children's subtrees are searched
+// one after another when none of the children matches.
+func selectFirst(node *html.Node, match func(*html.Node) bool, recursive bool) *html.Node {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if match(c) {
+			return c
+		}
+	}
+	if !recursive {
+		return nil
 	}
-	if len(selector.Tag) > 0 {
-		return firstWithTag(node, selector.Tag, selector.Recursive)
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if n := selectFirst(c, match, true); n != nil {
+			return n
+		}
 	}
 	return nil
 }
@@ -219,6 +511,28 @@ func firstWithId(node *html.Node, id string, recursive bool) *html.Node {
 	return nil
 }
 
+// AllWithAttrContains returns all descendants whose attribute contains substr in document order,
+// e.g. AllWithAttrContains(node, "href", "example.com"). The search is always recursive and case-sensitive.
+// An empty substr matches all descendants that have the attribute.
+func AllWithAttrContains(node *html.Node, key, substr string) []*html.Node {
+	return selectAll(node, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && hasAttr(n, key) && strings.Contains(Attr(n, key), substr)
+	}, true, 0)
+}
+
+// AllWithIds returns all descendants with one of the given ids in document order.
+// Since ids are supposed to be unique within a document, the search is always recursive.
+// Pages reusing an id yield all elements with that id.
+func AllWithIds(node *html.Node, ids ...string) []*html.Node {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return selectAll(node, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && hasAttr(n, "id") && set[Attr(n, "id")]
+	}, true, 0)
+}
+
 // FirstWithClassName returns the first child with the given class.
 func FirstWithClassName(node *html.Node, className string) *html.Node {
 	return firstWithClassName(node, className, false)
@@ -320,32 +634,105 @@ func allWithTag(node *html.Node, tagName string, recursive bool) []*html.Node {
 	return res
 }
 
+// FirstWithTagAndClass returns the first child with the given tag and class.
+func FirstWithTagAndClass(node *html.Node, tagName, className string) *html.Node {
+	return selectFirst(node, tagAndClass(tagName, className), false)
+}
+
+// FirstWithTagAndClassR is the recursive variant of FirstWithTagAndClass.
+func FirstWithTagAndClassR(node *html.Node, tagName, className string) *html.Node {
+	return selectFirst(node, tagAndClass(tagName, className), true)
+}
+
+// AllWithTagAndClass returns all children with the given tag and class.
+func AllWithTagAndClass(node *html.Node, tagName, className string) []*html.Node {
+	return selectAll(node, tagAndClass(tagName, className), false, 0)
+}
+
+// AllWithTagAndClassR is the recursive variant of AllWithTagAndClass.
+func AllWithTagAndClassR(node *html.Node, tagName, className string) []*html.Node {
+	return selectAll(node, tagAndClass(tagName, className), true, 0)
+}
+
+func tagAndClass(tagName, className string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == tagName && HasClass(n, className)
+	}
+}
+
+// Has returns true if the node has a descendant matching the selector.
+// All descendants are searched, no matter the Recursive flag of the selector. The node itself isn't matched.
+func Has(node *html.Node, selector Selector) bool {
+	return selectFirst(node, selector.matches, true) != nil
+}
+
+// HasClass returns true if the node has the given class.
+// The class attribute is scanned in place, so no memory is allocated.
 func HasClass(node *html.Node, className string) bool {
+	if len(className) == 0 {
+		return false
+	}
 	for _, a := range node.Attr {
 		if a.Key == "class" {
-			for _, class := range strings.Split(a.Val, " ") {
-				if class == className {
-					return true
-				}
-			}
-			return false
+			return containsClass(a.Val, className)
 		}
 	}
 	return false
 }
 
-func TextContent(node *html.Node) string {
+// containsClass returns true if the whitespace separated list of classes contains className.
+func containsClass(classes, className string) bool {
+	for i := 0; i < len(classes); {
+		for i < len(classes) && isClassSeparator(classes[i]) {
+			i++
+		}
+		start := i
+		for i < len(classes) && !isClassSeparator(classes[i]) {
+			i++
+		}
+		if i > start && classes[start:i] == className {
+			return true
+		}
+	}
+	return false
+}
+
+// isClassSeparator returns true for the ASCII whitespace characters that separate classes.
+func isClassSeparator(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\f' || ch == '\r'
+}
+
+// FirstChildText returns the text of the first text child of the node, or the text of the node itself if it is a text node.
+// The text is trimmed of all Unicode whitespace, including the non-breaking spaces of &nbsp;.
+func FirstChildText(node *html.Node) string {
 	if node.Type == html.TextNode {
-		return strings.Trim(node.Data, " \t\n\r")
+		return strings.TrimSpace(node.Data)
 	}
 	for c := node.FirstChild; c != nil; c = c.NextSibling {
 		if c.Type == html.TextNode {
-			return strings.Trim(c.Data, " \t\n\n")
+			return strings.TrimSpace(c.Data)
 		}
 	}
 	return ""
 }
 
+// TextContent returns the text of all descendant text nodes concatenated as is, like textContent in the DOM,
+// with all Unicode whitespace trimmed from the result. Unlike TextContentR, the text nodes aren't trimmed
+// and joined with spaces, so <b>soup</b>s yields "soups". Use FirstChildText for the first text child only.
+func TextContent(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return strings.TrimSpace(node.Data)
+	}
+	var b strings.Builder
+	walk(node, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+		return true
+	})
+	return strings.TrimSpace(b.String())
+}
+
 // Attr returns the attribute value or an empty string if the attribute isn't found.
 func Attr(node *html.Node, attr string) string {
 	for _, a := range node.Attr {
@@ -355,3 +742,109 @@ func Attr(node *html.Node, attr string) string {
 	}
 	return ""
 }
+
+// AttrBool interprets the attribute as a boolean attribute like disabled, checked or selected.
+// As in HTML, the attribute is true if it is present, no matter its value, so disabled="" and disabled="false"
+// both mean true.
+func AttrBool(node *html.Node, key string) bool {
+	return hasAttr(node, key)
+}
+
+// AttrFold is like Attr but compares attribute keys case-insensitively.
+// This helps with foreign content like SVG, where keys such as viewBox keep their camel case.
+func AttrFold(node *html.Node, attr string) string {
+	for _, a := range node.Attr {
+		if strings.EqualFold(a.Key, attr) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// AttrsWithPrefix returns all attributes whose key starts with the given prefix.
+func AttrsWithPrefix(node *html.Node, prefix string) map[string]string {
+	res := make(map[string]string)
+	for _, a := range node.Attr {
+		if strings.HasPrefix(a.Key, prefix) {
+			res[a.Key] = a.Val
+		}
+	}
+	return res
+}
+
+// DataInt parses the data attribute with the given name, e.g. "page" for data-page="3", as a decimal integer.
+// Surrounding whitespace is ignored. The second result is false if the attribute is missing or not an integer.
+func DataInt(node *html.Node, key string) (int, bool) {
+	v, err := strconv.Atoi(strings.TrimSpace(Attr(node, "data-"+key)))
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// Style returns the declarations of the inline style attribute, e.g. {"display": "none"} for style="display: none;".
+// Property names are lowercased, values are trimmed but otherwise kept as is, including a trailing !important.
+// If a property is declared more than once, the last declaration wins, just like in CSS.
+func Style(node *html.Node) map[string]string {
+	res := make(map[string]string)
+	for _, decl := range splitDeclarations(Attr(node, "style")) {
+		prop, val, ok := strings.Cut(decl, ":")
+		prop = strings.ToLower(strings.TrimSpace(prop))
+		if !ok || len(prop) == 0 {
+			continue
+		}
+		res[prop] = strings.TrimSpace(val)
+	}
+	return res
+}
+
+// splitDeclarations splits a list of CSS declarations at the semicolons that aren't within parentheses
+// or quoted strings, so that values like url(data:image/png;base64,...) stay intact.
+func splitDeclarations(s string) []string {
+	res := make([]string, 0)
+	depth := 0
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '\\':
+			i++
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			if depth > 0 {
+				depth--
+			}
+		case ch == ';' && depth == 0:
+			res = append(res, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(res, s[start:])
+}
+
+func hasAttr(node *html.Node, attr string) bool {
+	for _, a := range node.Attr {
+		if a.Key == attr {
+			return true
+		}
+	}
+	return false
+}
+
+// walk calls fn for every descendant of node in document order.
+// The children of a descendant are skipped if fn returns false for it.
+func walk(node *html.Node, fn func(*html.Node) bool) {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if fn(c) {
+			walk(c, fn)
+		}
+	}
+}
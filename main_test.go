@@ -0,0 +1,105 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+	"testing"
+)
+
+func TestHasClass(t *testing.T) {
+	tests := []struct {
+		class     string
+		className string
+		want      bool
+	}{
+		{"foo", "foo", true},
+		{"foo bar", "bar", true},
+		{"foo  bar", "bar", true},
+		{"foo\tbar", "bar", true},
+		{"foo\nbar", "bar", true},
+		{"foo\fbar\r", "bar", true},
+		{"  foo  ", "foo", true},
+		{"\tfoo\n", "foo", true},
+		{"foobar", "foo", false},
+		{"foo-bar", "foo", false},
+		{"Foo", "foo", false},
+		{"", "foo", false},
+		{"   ", "foo", false},
+		{"foo", "", false},
+		{"foo  bar", "", false},
+	}
+	for _, tt := range tests {
+		node := &html.Node{Type: html.ElementNode, Data: "div", Attr: []html.Attribute{{Key: "class", Val: tt.class}}}
+		if got := HasClass(node, tt.className); got != tt.want {
+			t.Errorf("HasClass(class=%q, %q) = %v, want %v", tt.class, tt.className, got, tt.want)
+		}
+	}
+	if HasClass(&html.Node{Type: html.ElementNode, Data: "div"}, "foo") {
+		t.Error("HasClass without class attribute = true, want false")
+	}
+}
+
+func BenchmarkHasClass(b *testing.B) {
+	node := &html.Node{Type: html.ElementNode, Data: "div", Attr: []html.Attribute{
+		{Key: "id", Val: "main"},
+		{Key: "class", Val: "card card--large\tproduct  featured"},
+	}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if !HasClass(node, "featured") {
+			b.Fatal("class not found")
+		}
+	}
+}
+
+func TestSelectAnyOverlappingSelectors(t *testing.T) {
+	root, err := html.Parse(strings.NewReader(`<h1 id="1"></h1><div id="2"><h2 id="3"></h2><h1 id="4"><h2 id="5"></h2></h1></div><h2 id="6"></h2>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := FirstWithTagR(root, "body")
+	res := SelectAny(body,
+		Selector{Tag: "h1", Recursive: true},
+		Selector{Tag: "*", Recursive: true},
+		Selector{Tag: "h2", Recursive: true},
+	)
+	ids := make([]string, 0, len(res))
+	for _, n := range res {
+		ids = append(ids, Attr(n, "id"))
+	}
+	if got, want := strings.Join(ids, " "), "1 2 3 4 5 6"; got != want {
+		t.Errorf("SelectAny returned %q, want %q", got, want)
+	}
+}
+
+func TestStyle(t *testing.T) {
+	tests := []struct {
+		style string
+		want  map[string]string
+	}{
+		{"display: none;", map[string]string{"display": "none"}},
+		{"COLOR:red; color: blue !important", map[string]string{"color": "blue !important"}},
+		{"background:url(data:image/png;base64,AA); display:none",
+			map[string]string{"background": "url(data:image/png;base64,AA)", "display": "none"}},
+		{`content: "a;b"; font-family: 'x;y', serif`, map[string]string{"content": `"a;b"`, "font-family": `'x;y', serif`}},
+		{`content: "a\";b"; top: 0`, map[string]string{"content": `"a\";b"`, "top": "0"}},
+		{";;invalid; :x; left: 1px", map[string]string{"left": "1px"}},
+	}
+	for _, tt := range tests {
+		node := &html.Node{Type: html.ElementNode, Data: "div", Attr: []html.Attribute{{Key: "style", Val: tt.style}}}
+		got := Style(node)
+		if len(got) != len(tt.want) {
+			t.Errorf("Style(%q) = %v, want %v", tt.style, got, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("Style(%q)[%q] = %q, want %q", tt.style, k, got[k], v)
+			}
+		}
+	}
+}
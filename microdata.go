@@ -0,0 +1,96 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+)
+
+// Microdata extracts the microdata items of the node's subtree.
+// See Microdata for details.
+func (n *Node) Microdata() map[string]any {
+	return Microdata(n.backing)
+}
+
+// Microdata extracts the microdata items (itemscope, itemtype, itemprop) of the node's subtree,
+// including the node itself. The result follows the JSON format of the microdata specification:
+//
+//	{"items": [{"type": ["https://schema.org/Product"], "properties": {"name": ["Soup"]}}]}
+//
+// Property values are always lists. They are nested items for elements with itemscope,
+// the content, href, src, data, value or datetime attribute depending on the element
+// and the text content as returned by TextContentR otherwise. itemref isn't supported.
+func Microdata(node *html.Node) map[string]any {
+	items := make([]any, 0)
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode && hasAttr(n, "itemscope") && !hasAttr(n, "itemprop") {
+			items = append(items, microdataItem(n))
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(node)
+	return map[string]any{"items": items}
+}
+
+func microdataItem(node *html.Node) map[string]any {
+	item := make(map[string]any)
+	if types := strings.Fields(Attr(node, "itemtype")); len(types) > 0 {
+		item["type"] = types
+	}
+	if id := strings.TrimSpace(Attr(node, "itemid")); len(id) > 0 {
+		item["id"] = id
+	}
+	props := make(map[string][]any)
+	walk(node, func(c *html.Node) bool {
+		if c.Type != html.ElementNode {
+			return false
+		}
+		names := strings.Fields(Attr(c, "itemprop"))
+		scope := hasAttr(c, "itemscope")
+		if len(names) > 0 {
+			var value any
+			if scope {
+				value = microdataItem(c)
+			} else {
+				value = microdataValue(c)
+			}
+			for _, name := range names {
+				props[name] = append(props[name], value)
+			}
+		}
+		// nested items own the properties below them
+		return !scope
+	})
+	properties := make(map[string]any, len(props))
+	for name, values := range props {
+		properties[name] = values
+	}
+	item["properties"] = properties
+	return item
+}
+
+func microdataValue(node *html.Node) string {
+	switch node.Data {
+	case "meta":
+		return Attr(node, "content")
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		return Attr(node, "src")
+	case "a", "area", "link":
+		return Attr(node, "href")
+	case "object":
+		return Attr(node, "data")
+	case "data", "meter":
+		return Attr(node, "value")
+	case "time":
+		if hasAttr(node, "datetime") {
+			return Attr(node, "datetime")
+		}
+	}
+	return TextContentR(node)
+}
@@ -0,0 +1,357 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+)
+
+// Append adds child as the last child of the node. See Append for details.
+func (n *Node) Append(child *Node) {
+	Append(n.backing, child.backing)
+}
+
+// AppendText adds a text node as the last child of the node.
+func (n *Node) AppendText(s string) {
+	Append(n.backing, &html.Node{Type: html.TextNode, Data: s})
+}
+
+// Detach removes the node from its parent. The node's children are kept.
+func (n *Node) Detach() {
+	Detach(n.backing)
+}
+
+// Empty removes all children from the node.
+func (n *Node) Empty() {
+	Empty(n.backing)
+}
+
+// ExpandShadowRoots replaces the declarative shadow roots in the node's subtree with their content.
+// See ExpandShadowRoots for details.
+func (n *Node) ExpandShadowRoots() {
+	ExpandShadowRoots(n.backing)
+}
+
+// Normalize merges adjacent text nodes and removes empty ones in the node's subtree.
+// See Normalize for details.
+func (n *Node) Normalize() {
+	Normalize(n.backing)
+}
+
+// Prepend adds child as the first child of the node. See Prepend for details.
+func (n *Node) Prepend(child *Node) {
+	Prepend(n.backing, child.backing)
+}
+
+// PrependText adds a text node as the first child of the node.
+func (n *Node) PrependText(s string) {
+	Prepend(n.backing, &html.Node{Type: html.TextNode, Data: s})
+}
+
+// RemoveAll detaches all nodes that SelectAll returns for the selector and returns how many were removed.
+// See RemoveAll for details.
+func (n *Node) RemoveAll(selector Selector) int {
+	return RemoveAll(n.backing, selector)
+}
+
+// ReplaceWith puts replacement in the place of the node. See ReplaceWith for details.
+func (n *Node) ReplaceWith(replacement *Node) {
+	ReplaceWith(n.backing, replacement.backing)
+}
+
+// ReplaceWithHTML puts the parsed HTML in the place of the node.
+// See ReplaceWithHTML for details.
+func (n *Node) ReplaceWithHTML(s string) error {
+	return ReplaceWithHTML(n.backing, s)
+}
+
+// SetInnerHTML replaces the children of the node with the parsed HTML.
+// See SetInnerHTML for details.
+func (n *Node) SetInnerHTML(s string) error {
+	return SetInnerHTML(n.backing, s)
+}
+
+// Swap exchanges the positions of the node and other in the tree.
+// See Swap for details.
+func (n *Node) Swap(other *Node) {
+	Swap(n.backing, other.backing)
+}
+
+// TrimWhitespace removes formatting whitespace from the children of the node.
+// See TrimWhitespace for details.
+func (n *Node) TrimWhitespace(opts ...TrimOption) {
+	TrimWhitespace(n.backing, opts...)
+}
+
+// Unwrap replaces the node with its children. See Unwrap for details.
+func (n *Node) Unwrap() {
+	Unwrap(n.backing)
+}
+
+// Wrap puts wrapper in the place of the node and the node into wrapper. See Wrap for details.
+func (n *Node) Wrap(wrapper *Node) {
+	Wrap(n.backing, wrapper.backing)
+}
+
+// Append adds child as the last child of the node. If child is attached to a tree, it is moved.
+// Append panics if child is the node or one of its ancestors.
+func Append(node, child *html.Node) {
+	insert(node, child, nil)
+}
+
+// Prepend adds child as the first child of the node. If child is attached to a tree, it is moved.
+// Prepend panics if child is the node or one of its ancestors.
+func Prepend(node, child *html.Node) {
+	insert(node, child, node.FirstChild)
+}
+
+// Detach removes the node from its parent. The node's children are kept.
+// Detaching a node without a parent has no effect.
+func Detach(node *html.Node) {
+	if node.Parent != nil {
+		node.Parent.RemoveChild(node)
+	}
+}
+
+// ExpandShadowRoots replaces the declarative shadow roots in the node's subtree, that is <template> elements
+// with a shadowrootmode of open or closed, with their content. The content ends up before the light DOM children
+// of the host, where the text helpers, which skip templates, and all other queries see it like regular children.
+// Slots aren't assigned. Call ExpandShadowRoots right after parsing pages that use declarative shadow DOM.
+func ExpandShadowRoots(node *html.Node) {
+	roots := selectAll(node, isShadowRoot, true, 0)
+	if isShadowRoot(node) {
+		roots = append([]*html.Node{node}, roots...)
+	}
+	for _, r := range roots {
+		Unwrap(r)
+	}
+}
+
+func isShadowRoot(node *html.Node) bool {
+	if node.Type != html.ElementNode || node.Data != "template" {
+		return false
+	}
+	mode := strings.ToLower(strings.TrimSpace(Attr(node, "shadowrootmode")))
+	return mode == "open" || mode == "closed"
+}
+
+// Normalize merges adjacent text nodes and removes empty text nodes in the node's subtree, like normalize() in the DOM.
+// The parser never produces adjacent text nodes, but mutations like Unwrap or AppendText do.
+func Normalize(node *html.Node) {
+	for c := node.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type != html.TextNode {
+			Normalize(c)
+			c = next
+			continue
+		}
+		for next != nil && next.Type == html.TextNode {
+			c.Data += next.Data
+			Detach(next)
+			next = c.NextSibling
+		}
+		if len(c.Data) == 0 {
+			Detach(c)
+		}
+		c = next
+	}
+}
+
+// ReplaceWith puts replacement in the place of the node and detaches the node.
+// If replacement is attached to a tree, it is moved. Replacing a node without a parent has no effect.
+// ReplaceWith panics if replacement is an ancestor of the node.
+func ReplaceWith(node, replacement *html.Node) {
+	if node == replacement || node.Parent == nil {
+		return
+	}
+	if Contains(replacement, node) {
+		panic("soup: ReplaceWith called with an ancestor of the node")
+	}
+	insert(node.Parent, replacement, node)
+	Detach(node)
+}
+
+// ReplaceWithHTML puts the parsed HTML in the place of the node and detaches the node. The HTML is parsed
+// as a fragment in the context of the node's parent, like SetInnerHTML does for the node itself, so that
+// e.g. a row replacing a row of a <tbody> is kept. Replacing a node without a parent has no effect.
+// The node is left untouched if parsing fails.
+func ReplaceWithHTML(node *html.Node, s string) error {
+	parent := node.Parent
+	if parent == nil {
+		return nil
+	}
+	context := parent
+	if context.Type != html.ElementNode {
+		context = nil
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(s), context)
+	if err != nil {
+		return err
+	}
+	for _, c := range nodes {
+		insert(parent, c, node)
+	}
+	Detach(node)
+	return nil
+}
+
+// Unwrap replaces the node with its children. Unwrapping a node without a parent has no effect.
+func Unwrap(node *html.Node) {
+	parent := node.Parent
+	if parent == nil {
+		return
+	}
+	for c := node.FirstChild; c != nil; c = node.FirstChild {
+		insert(parent, c, node)
+	}
+	Detach(node)
+}
+
+// Wrap puts wrapper in the place of the node and appends the node to the children of wrapper.
+// If wrapper is attached to a tree, it is moved. A node without a parent is just appended to wrapper.
+// Wrap panics if wrapper is the node, one of its descendants or one of its ancestors.
+func Wrap(node, wrapper *html.Node) {
+	if Contains(node, wrapper) || Contains(wrapper, node) {
+		panic("soup: Wrap called with the node, one of its descendants or one of its ancestors")
+	}
+	if node.Parent != nil {
+		insert(node.Parent, wrapper, node)
+	}
+	insert(wrapper, node, nil)
+}
+
+// Empty removes all children from the node.
+// The removed children are detached and can be attached to another node.
+func Empty(node *html.Node) {
+	for node.FirstChild != nil {
+		Detach(node.FirstChild)
+	}
+}
+
+// RemoveAll detaches all nodes that SelectAll returns for the selector and returns how many were removed.
+// Matches are collected before anything is removed. Matches inside an already removed match
+// are removed along with it and aren't counted.
+func RemoveAll(node *html.Node, selector Selector) int {
+	removed := 0
+	var last *html.Node
+	for _, m := range SelectAll(node, selector) {
+		// matches are in document order, so a nested match always follows its removed ancestor
+		if last != nil && Contains(last, m) {
+			continue
+		}
+		Detach(m)
+		last = m
+		removed++
+	}
+	return removed
+}
+
+// SetInnerHTML replaces the children of the node with the parsed HTML. The HTML is parsed as a fragment
+// in the context of the node, so that e.g. rows are kept when setting the content of a <tbody>.
+// The children are left untouched if parsing fails.
+func SetInnerHTML(node *html.Node, s string) error {
+	children, err := html.ParseFragment(strings.NewReader(s), node)
+	if err != nil {
+		return err
+	}
+	Empty(node)
+	for _, c := range children {
+		insert(node, c, nil)
+	}
+	return nil
+}
+
+// Swap exchanges the positions of a and b in the tree. The nodes may be siblings or live in
+// different trees. If one of them is detached, the other one is detached in exchange.
+// Swap panics if one node is an ancestor of the other, because the result would contain a cycle.
+func Swap(a, b *html.Node) {
+	if a == b {
+		return
+	}
+	if Contains(a, b) || Contains(b, a) {
+		panic("soup: Swap called with an ancestor of the other node")
+	}
+	pa, pb := a.Parent, b.Parent
+	ma, mb := &html.Node{Type: html.CommentNode}, &html.Node{Type: html.CommentNode}
+	if pa != nil {
+		insert(pa, ma, a)
+	}
+	if pb != nil {
+		insert(pb, mb, b)
+	}
+	Detach(a)
+	Detach(b)
+	if pa != nil {
+		insert(pa, b, ma)
+		Detach(ma)
+	}
+	if pb != nil {
+		insert(pb, a, mb)
+		Detach(mb)
+	}
+}
+
+// TrimOption configures TrimWhitespace.
+type TrimOption func(*trimConfig)
+
+// WithCollapseBetweenBlocks makes TrimWhitespace also remove whitespace-only text children between two
+// block elements, like the indentation between paragraphs.
+func WithCollapseBetweenBlocks() TrimOption {
+	return func(c *trimConfig) {
+		c.collapseBetweenBlocks = true
+	}
+}
+
+type trimConfig struct {
+	collapseBetweenBlocks bool
+}
+
+// TrimWhitespace removes whitespace-only text children at the start and the end of the node's children.
+// With WithCollapseBetweenBlocks, whitespace-only text children between two block elements are removed as well.
+// Whitespace between inline elements is meaningful and always kept, so <b>a</b> <i>b</i> still renders with a space.
+// Whitespace is tested with IsWhitespace, so text consisting of non-breaking spaces is kept as well.
+func TrimWhitespace(node *html.Node, opts ...TrimOption) {
+	config := trimConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	isBlank := func(n *html.Node) bool {
+		return n != nil && IsWhitespace(n)
+	}
+	isBlock := func(n *html.Node) bool {
+		return n != nil && n.Type == html.ElementNode && blockElements[n.Data]
+	}
+	for isBlank(node.FirstChild) {
+		Detach(node.FirstChild)
+	}
+	for isBlank(node.LastChild) {
+		Detach(node.LastChild)
+	}
+	if !config.collapseBetweenBlocks {
+		return
+	}
+	for c := node.FirstChild; c != nil; {
+		next := c.NextSibling
+		if isBlank(c) && isBlock(c.PrevSibling) && isBlock(next) {
+			Detach(c)
+		}
+		c = next
+	}
+}
+
+// insert moves child before ref in the children of parent, or to the end if ref is nil.
+// All mutations are built on insert and Detach, which keep the parent and sibling pointers consistent.
+// insert panics if parent is child or one of its descendants, because the tree would contain a cycle.
+func insert(parent, child, ref *html.Node) {
+	if Contains(child, parent) {
+		panic("soup: cannot insert a node into itself or one of its descendants")
+	}
+	if ref == child {
+		ref = child.NextSibling
+	}
+	Detach(child)
+	parent.InsertBefore(child, ref)
+}
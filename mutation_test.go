@@ -0,0 +1,185 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"golang.org/x/net/html"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestMutationsKeepTreeConsistent(t *testing.T) {
+	root, err := html.Parse(strings.NewReader(`<div id="a"><p>one <b>two</b> three</p><span>four</span><div><p>five</p></div></div><p>six</p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := make([]*html.Node, 0)
+	walk(root, func(n *html.Node) bool {
+		nodes = append(nodes, n)
+		return true
+	})
+	for i := 0; i < 5; i++ {
+		nodes = append(nodes, &html.Node{Type: html.ElementNode, Data: "span"}, &html.Node{Type: html.TextNode, Data: "x"})
+	}
+	elements := func() []*html.Node {
+		res := make([]*html.Node, 0)
+		for _, n := range nodes {
+			if n.Type == html.ElementNode {
+				res = append(res, n)
+			}
+		}
+		return res
+	}()
+	isAncestor := func(a, b *html.Node) bool { return Contains(a, b) || Contains(b, a) }
+
+	rnd := rand.New(rand.NewSource(1))
+	ops := []string{"Append", "Prepend", "Detach", "ReplaceWith", "Wrap", "Unwrap", "Swap"}
+	for step := 0; step < 5000; step++ {
+		op := ops[rnd.Intn(len(ops))]
+		a := nodes[rnd.Intn(len(nodes))]
+		b := nodes[rnd.Intn(len(nodes))]
+		e := elements[rnd.Intn(len(elements))]
+		switch op {
+		case "Append":
+			if !Contains(a, e) {
+				Append(e, a)
+			}
+		case "Prepend":
+			if !Contains(a, e) {
+				Prepend(e, a)
+			}
+		case "Detach":
+			Detach(a)
+		case "ReplaceWith":
+			if !Contains(b, a) {
+				ReplaceWith(a, b)
+			}
+		case "Wrap":
+			if !isAncestor(a, e) {
+				Wrap(a, e)
+			}
+		case "Unwrap":
+			Unwrap(a)
+		case "Swap":
+			if a == b || !isAncestor(a, b) {
+				Swap(a, b)
+			}
+		}
+		for _, n := range append(nodes, root) {
+			if msg := checkLinks(n, len(nodes)+1); msg != "" {
+				t.Fatalf("step %d (%s): %s", step, op, msg)
+			}
+		}
+		if err := html.Render(io.Discard, root); err != nil {
+			t.Fatalf("step %d (%s): render: %v", step, op, err)
+		}
+	}
+}
+
+func TestMutationsPanicOnCycles(t *testing.T) {
+	tests := map[string]func(parent, child *html.Node){
+		"Append":      func(parent, child *html.Node) { Append(child, parent) },
+		"Prepend":     func(parent, child *html.Node) { Prepend(child, parent) },
+		"ReplaceWith": func(parent, child *html.Node) { ReplaceWith(child, parent) },
+		"Wrap":        func(parent, child *html.Node) { Wrap(parent, child) },
+		"WrapInside":  func(parent, child *html.Node) { Wrap(child, parent) },
+		"Swap":        func(parent, child *html.Node) { Swap(parent, child) },
+	}
+	for name, fn := range tests {
+		t.Run(name, func(t *testing.T) {
+			root, _ := html.Parse(strings.NewReader(`<div><p>x</p></div>`))
+			div := FirstWithTagR(root, "div")
+			p := FirstWithTagR(root, "p")
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+				if msg := checkLinks(root, 100); msg != "" {
+					t.Error(msg)
+				}
+			}()
+			fn(div, p)
+		})
+	}
+}
+
+// checkLinks returns a description of the first inconsistency of the pointers around n or an empty string.
+func checkLinks(n *html.Node, maxDepth int) string {
+	depth := 0
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p == n || depth > maxDepth {
+			return "cycle in parent chain of " + n.Data
+		}
+		depth++
+	}
+	if (n.FirstChild == nil) != (n.LastChild == nil) {
+		return "only one of FirstChild and LastChild is set on " + n.Data
+	}
+	var prev *html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Parent != n {
+			return "child " + c.Data + " doesn't point to its parent " + n.Data
+		}
+		if c.PrevSibling != prev {
+			return "PrevSibling of " + c.Data + " is inconsistent"
+		}
+		prev = c
+	}
+	if n.LastChild != prev {
+		return "LastChild of " + n.Data + " is inconsistent"
+	}
+	if n.Parent == nil && (n.PrevSibling != nil || n.NextSibling != nil) {
+		return "detached node " + n.Data + " has siblings"
+	}
+	return ""
+}
+
+func TestRemoveWhileIteratingResults(t *testing.T) {
+	root, err := Parse(strings.NewReader(`<div><p>a</p><div><p>b<p>c</p></div><p>d</p></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed := 0
+	for _, p := range root.AllWithTagR("p") {
+		p.Detach()
+		removed++
+	}
+	if removed != 4 {
+		t.Errorf("removed %d nodes, want 4", removed)
+	}
+	if got := root.AllWithTagR("p"); len(got) != 0 {
+		t.Errorf("%d <p> left after removing all of them", len(got))
+	}
+	if msg := checkLinks(root.backing, 100); msg != "" {
+		t.Error(msg)
+	}
+	if got, want := mustHTML(t, root), `<html><head></head><body><div><div></div></div></body></html>`; got != want {
+		t.Errorf("HTML() = %s, want %s", got, want)
+	}
+}
+
+func TestTrimWhitespace(t *testing.T) {
+	const fragment = "<div>\n\t<p>a</p>\n\t<p>b</p>\n\t<b>c</b> <i>d</i>&nbsp;<p>e</p>\n</div>"
+	tests := []struct {
+		name string
+		opts []TrimOption
+		want string
+	}{
+		{"ends", nil, "<div><p>a</p>\n\t<p>b</p>\n\t<b>c</b> <i>d</i>\u00a0<p>e</p></div>"},
+		{"between blocks", []TrimOption{WithCollapseBetweenBlocks()}, "<div><p>a</p><p>b</p>\n\t<b>c</b> <i>d</i>\u00a0<p>e</p></div>"},
+	}
+	for _, tt := range tests {
+		nodes, err := ParseFragmentString(fragment, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[0].TrimWhitespace(tt.opts...)
+		if got := mustHTML(t, nodes[0]); got != tt.want {
+			t.Errorf("%s: HTML() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+)
+
+// AncestorsMatching returns all ancestors matching the selector, nearest first.
+// See AncestorsMatching for details.
+func (n *Node) AncestorsMatching(selector Selector) []*Node {
+	return newNodes(AncestorsMatching(n.backing, selector))
+}
+
+// Closest returns the node itself or its nearest ancestor matching the selector.
+// Recursive is ignored.
+func (n *Node) Closest(selector Selector) *Node {
+	res := Closest(n.backing, selector)
+	if res != nil {
+		return newNode(res)
+	}
+	return nil
+}
+
+// ClosestUntil is like Closest but stops at the first ancestor matching boundary.
+// See ClosestUntil for details.
+func (n *Node) ClosestUntil(match, boundary Selector) *Node {
+	res := ClosestUntil(n.backing, match, boundary)
+	if res != nil {
+		return newNode(res)
+	}
+	return nil
+}
+
+// ClosestWithClassName returns the node itself or its nearest ancestor with the given class.
+func (n *Node) ClosestWithClassName(className string) *Node {
+	return n.Closest(Selector{ClassName: className})
+}
+
+// ClosestWithTag returns the node itself or its nearest ancestor with the given tag.
+func (n *Node) ClosestWithTag(tag string) *Node {
+	return n.Closest(Selector{Tag: tag})
+}
+
+// ComparePosition compares the position of the node and other in document order.
+// See ComparePosition for details.
+func (n *Node) ComparePosition(other *Node) int {
+	return ComparePosition(n.backing, other.backing)
+}
+
+// Contains returns true if other is the node itself or one of its descendants.
+func (n *Node) Contains(other *Node) bool {
+	return Contains(n.backing, other.backing)
+}
+
+// HasChildren returns true if the node has at least one element child. Text nodes don't count.
+func (n *Node) HasChildren() bool {
+	return HasChildren(n.backing)
+}
+
+// IsLeaf returns true if the node has no element children. It may still contain text.
+func (n *Node) IsLeaf() bool {
+	return IsLeaf(n.backing)
+}
+
+// IsWhitespace returns true if the node is a text node that contains only whitespace.
+func (n *Node) IsWhitespace() bool {
+	return IsWhitespace(n.backing)
+}
+
+// NextAll returns all following element siblings.
+func (n *Node) NextAll() []*Node {
+	return newNodes(NextAll(n.backing))
+}
+
+// NextUntil returns the following element siblings up to, but excluding, the first one matching the selector.
+// See NextUntil for details.
+func (n *Node) NextUntil(selector Selector) []*Node {
+	return newNodes(NextUntil(n.backing, selector))
+}
+
+// PrevAll returns all preceding element siblings in document order.
+func (n *Node) PrevAll() []*Node {
+	return newNodes(PrevAll(n.backing))
+}
+
+// PrevUntil returns the preceding element siblings back to, but excluding, the first one matching the selector.
+// See PrevUntil for details.
+func (n *Node) PrevUntil(selector Selector) []*Node {
+	return newNodes(PrevUntil(n.backing, selector))
+}
+
+// Root returns the topmost ancestor of the node, usually the document, or the node itself if it has no parent.
+func (n *Node) Root() *Node {
+	return newNode(Root(n.backing))
+}
+
+// TagPath returns the tags of the node's element ancestors and the node itself, starting at the root.
+// See TagPath for details.
+func (n *Node) TagPath() []string {
+	return TagPath(n.backing)
+}
+
+// TemplateContent returns the root of a <template> element's content or nil if the node isn't a template.
+// The parser stores template content as regular children of the element, so the returned node
+// is the template itself and the content can be queried like any other subtree.
+func (n *Node) TemplateContent() *Node {
+	if n.backing.Type == html.ElementNode && n.backing.Data == "template" {
+		return n
+	}
+	return nil
+}
+
+// Between returns the element siblings after start and before end.
+// If end is nil, all following element siblings of start are returned.
+// If end is not a following sibling of start, the result is empty.
+func Between(start, end *Node) []*Node {
+	var stop *html.Node
+	if end != nil {
+		stop = end.backing
+	}
+	res := make([]*html.Node, 0)
+	for s := start.backing.NextSibling; s != stop; s = s.NextSibling {
+		if s == nil {
+			return []*Node{}
+		}
+		if s.Type == html.ElementNode {
+			res = append(res, s)
+		}
+	}
+	return newNodes(res)
+}
+
+// AncestorsMatching returns all ancestors matching the selector, nearest first. Unlike Closest,
+// the node itself isn't included. Recursive and Limit are ignored.
+func AncestorsMatching(node *html.Node, selector Selector) []*html.Node {
+	res := make([]*html.Node, 0)
+	for p := node.Parent; p != nil; p = p.Parent {
+		if selector.matches(p) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// Closest returns the node itself or its nearest ancestor matching the selector.
+// Recursive is ignored.
+func Closest(node *html.Node, selector Selector) *html.Node {
+	return closest(node, selector.matches)
+}
+
+// ClosestUntil is like Closest but returns nil once it reaches a node matching boundary.
+// A node matching both selectors is returned.
+func ClosestUntil(node *html.Node, match, boundary Selector) *html.Node {
+	for p := node; p != nil; p = p.Parent {
+		if match.matches(p) {
+			return p
+		}
+		if boundary.matches(p) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func closest(node *html.Node, match func(*html.Node) bool) *html.Node {
+	for p := node; p != nil; p = p.Parent {
+		if match(p) {
+			return p
+		}
+	}
+	return nil
+}
+
+// ComparePosition returns -1 if a comes before b in document order, 1 if it comes after b and 0 if they are the same node.
+// Ancestors come before their descendants. Nodes of different trees have no order and compare as 0.
+// The result can be used to sort nodes with sort.Slice.
+func ComparePosition(a, b *html.Node) int {
+	if a == b {
+		return 0
+	}
+	pa, pb := path(a), path(b)
+	if pa[0] != pb[0] {
+		return 0
+	}
+	i := 0
+	for i < len(pa) && i < len(pb) && pa[i] == pb[i] {
+		i++
+	}
+	if i == len(pa) {
+		return -1
+	}
+	if i == len(pb) {
+		return 1
+	}
+	for s := pa[i].NextSibling; s != nil; s = s.NextSibling {
+		if s == pb[i] {
+			return -1
+		}
+	}
+	return 1
+}
+
+// path returns the node and its ancestors, starting at the root.
+func path(node *html.Node) []*html.Node {
+	res := make([]*html.Node, 0)
+	for p := node; p != nil; p = p.Parent {
+		res = append(res, p)
+	}
+	reverse(res)
+	return res
+}
+
+// Contains returns true if other is the node itself or one of its descendants.
+func Contains(node, other *html.Node) bool {
+	for p := other; p != nil; p = p.Parent {
+		if p == node {
+			return true
+		}
+	}
+	return false
+}
+
+// HasChildren returns true if the node has at least one element child. Text nodes don't count.
+func HasChildren(node *html.Node) bool {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLeaf returns true if the node has no element children. It may still contain text.
+func IsLeaf(node *html.Node) bool {
+	return !HasChildren(node)
+}
+
+// IsWhitespace returns true if the node is a text node that contains only whitespace, like the formatting
+// between elements. Whitespace means the ASCII whitespace of HTML: space, tab, line feed, form feed and carriage return.
+// Empty text nodes are whitespace as well.
+func IsWhitespace(node *html.Node) bool {
+	return node.Type == html.TextNode && len(strings.Trim(node.Data, " \t\n\f\r")) == 0
+}
+
+// NextAll returns all following element siblings.
+func NextAll(node *html.Node) []*html.Node {
+	res := make([]*html.Node, 0)
+	for s := node.NextSibling; s != nil; s = s.NextSibling {
+		if s.Type == html.ElementNode {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// PrevAll returns all preceding element siblings in document order.
+func PrevAll(node *html.Node) []*html.Node {
+	res := make([]*html.Node, 0)
+	for s := node.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			res = append(res, s)
+		}
+	}
+	reverse(res)
+	return res
+}
+
+// NextUntil returns the following element siblings up to, but excluding, the first one matching the selector,
+// e.g. the content after a heading until the next heading. If no sibling matches, all following element siblings
+// are returned like NextAll. Only the siblings themselves are matched, so Recursive and Limit are ignored.
+func NextUntil(node *html.Node, selector Selector) []*html.Node {
+	res := make([]*html.Node, 0)
+	for s := node.NextSibling; s != nil; s = s.NextSibling {
+		if s.Type != html.ElementNode {
+			continue
+		}
+		if selector.matches(s) {
+			break
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+// PrevUntil returns the preceding element siblings back to, but excluding, the first one matching the selector,
+// in document order. If no sibling matches, all preceding element siblings are returned like PrevAll.
+// Only the siblings themselves are matched, so Recursive and Limit are ignored.
+func PrevUntil(node *html.Node, selector Selector) []*html.Node {
+	res := make([]*html.Node, 0)
+	for s := node.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type != html.ElementNode {
+			continue
+		}
+		if selector.matches(s) {
+			break
+		}
+		res = append(res, s)
+	}
+	reverse(res)
+	return res
+}
+
+// Root returns the topmost ancestor of the node, usually the document, or the node itself if it has no parent.
+func Root(node *html.Node) *html.Node {
+	for node.Parent != nil {
+		node = node.Parent
+	}
+	return node
+}
+
+// TagPath returns the tags of the node's element ancestors and the node itself, starting at the root,
+// e.g. ["html", "body", "div", "ul", "li"]. Nodes that aren't elements, like the document, are left out.
+// Elements with the same tag path are structurally similar, which helps grouping them.
+func TagPath(node *html.Node) []string {
+	res := make([]string, 0)
+	for p := node; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode {
+			res = append(res, p.Data)
+		}
+	}
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+	return res
+}
+
+func reverse(nodes []*html.Node) {
+	for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"compress/gzip"
+	"fmt"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ParseGzip is like Parse but decompresses the gzip compressed input first.
+func ParseGzip(r io.Reader) (*Node, error) {
+	z, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	return Parse(z)
+}
+
+// ParseOpts is like Parse but passes the options to the parser, e.g. html.ParseOptionEnableScripting(false)
+// to parse the content of <noscript> elements as markup.
+func ParseOpts(r io.Reader, opts ...html.ParseOption) (*Node, error) {
+	root, err := html.ParseWithOptions(r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return newNode(root), nil
+}
+
+// ParseFragment parses a list of nodes from a reader as if they were the children of an element
+// with the given context tag, e.g. "tbody" for table rows. An empty context defaults to "body".
+func ParseFragment(r io.Reader, context string) ([]*Node, error) {
+	nodes, err := html.ParseFragment(r, contextElement(context))
+	if err != nil {
+		return nil, err
+	}
+	return newNodes(nodes), nil
+}
+
+// ParseFragmentString is like ParseFragment but parses from a string.
+func ParseFragmentString(s, context string) ([]*Node, error) {
+	return ParseFragment(strings.NewReader(s), context)
+}
+
+// ParseInContext is like ParseFragmentString but additionally sets the attributes of the context element.
+// The insertion mode of the parser depends on the context, so e.g. cells are only kept with a "tr" context.
+// An empty context tag defaults to "body".
+func ParseInContext(s string, contextTag string, contextAttrs map[string]string) ([]*Node, error) {
+	context := contextElement(contextTag)
+	keys := make([]string, 0, len(contextAttrs))
+	for k := range contextAttrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		context.Attr = append(context.Attr, html.Attribute{Key: k, Val: contextAttrs[k]})
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(s), context)
+	if err != nil {
+		return nil, err
+	}
+	return newNodes(nodes), nil
+}
+
+// NoscriptContent returns the fallback markup of all <noscript> elements in the node's subtree.
+// See NoscriptContent for details.
+func (n *Node) NoscriptContent(scripting bool) []*Node {
+	return newNodes(NoscriptContent(n.backing, scripting))
+}
+
+// NoscriptContent returns the fallback markup of all <noscript> elements in the node's subtree, including the node itself,
+// in document order. scripting tells how the document was parsed. Parse and ParseFile enable scripting, which is
+// also the default of ParseOpts.
+//
+// With scripting enabled, the parser keeps the content of <noscript> as raw text, so fallbacks like the <img>
+// of lazy loading scripts are invisible to queries. NoscriptContent parses that text as a fragment in a <body>
+// context with scripting disabled. The parsed nodes are detached from the tree.
+//
+// With scripting disabled, the content already is markup and the children of each <noscript> are returned as is.
+// Its text nodes are never parsed again: the parser has already decoded their entities,
+// so parsing them would turn escaped text like &lt;img&gt; into elements.
+func NoscriptContent(node *html.Node, scripting bool) []*html.Node {
+	res := make([]*html.Node, 0)
+	add := func(n *html.Node) {
+		if n.Type != html.ElementNode || n.Data != "noscript" {
+			return
+		}
+		if !scripting {
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				res = append(res, c)
+			}
+			return
+		}
+		var b strings.Builder
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode {
+				b.WriteString(c.Data)
+			}
+		}
+		nodes, err := html.ParseFragmentWithOptions(strings.NewReader(b.String()), contextElement("body"), html.ParseOptionEnableScripting(false))
+		if err == nil {
+			res = append(res, nodes...)
+		}
+	}
+	add(node)
+	walk(node, func(c *html.Node) bool {
+		add(c)
+		return c.Type == html.ElementNode && c.Data != "noscript"
+	})
+	return res
+}
+
+func contextElement(tag string) *html.Node {
+	if len(tag) == 0 {
+		tag = "body"
+	}
+	tag = strings.ToLower(tag)
+	return &html.Node{Type: html.ElementNode, Data: tag, DataAtom: atom.Lookup([]byte(tag))}
+}
+
+// ParseFile parses a node from the file at path.
+// The charset is detected from a byte order mark or a meta tag and defaults to UTF-8.
+func ParseFile(path string) (*Node, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := charset.NewReader(f, "")
+	if err != nil {
+		return nil, err
+	}
+	return Parse(r)
+}
+
+// ParseGlob parses all files matching the pattern with ParseFile.
+// The resulting nodes are keyed by file path.
+func ParseGlob(pattern string) (map[string]*Node, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]*Node, len(paths))
+	for _, path := range paths {
+		n, err := ParseFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		res[path] = n
+	}
+	return res, nil
+}
+
+// ParseAll parses the documents concurrently with ParseAllN, using GOMAXPROCS workers.
+func ParseAll(readers []io.Reader) ([]*Node, []error) {
+	return ParseAllN(readers, 0)
+}
+
+// ParseAllN parses the documents concurrently with at most workers goroutines. A limit of 0 or less means GOMAXPROCS.
+// The results are parallel to readers: for each reader, either the node or the error is set.
+// Each reader is read by a single goroutine, but different readers are read at the same time,
+// so they must not share state.
+func ParseAllN(readers []io.Reader, workers int) ([]*Node, []error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(readers) {
+		workers = len(readers)
+	}
+	nodes := make([]*Node, len(readers))
+	errs := make([]error, len(readers))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				nodes[i], errs[i] = Parse(readers[i])
+			}
+		}()
+	}
+	for i := range readers {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return nodes, errs
+}
@@ -0,0 +1,40 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+	"testing"
+)
+
+func TestNoscriptContent(t *testing.T) {
+	const doc = `<body><noscript><img src="a.jpg"><p>x</p></noscript><noscript>&lt;img src=x onerror=alert(1)&gt;</noscript>`
+	render := func(nodes []*Node) string {
+		var b strings.Builder
+		for _, n := range nodes {
+			b.WriteString(mustHTML(t, n))
+		}
+		return b.String()
+	}
+	tests := []struct {
+		name      string
+		opts      []html.ParseOption
+		scripting bool
+	}{
+		{"scripting", nil, true},
+		{"no scripting", []html.ParseOption{html.ParseOptionEnableScripting(false)}, false},
+	}
+	for _, tt := range tests {
+		root, err := ParseOpts(strings.NewReader(doc), tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := render(root.NoscriptContent(tt.scripting))
+		if want := `<img src="a.jpg"/><p>x</p>&lt;img src=x onerror=alert(1)&gt;`; got != want {
+			t.Errorf("%s: NoscriptContent() = %s, want %s", tt.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"bytes"
+	"golang.org/x/net/html"
+	"io"
+	"strings"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity above which buffers aren't returned to the pool,
+// so that a single huge document doesn't pin its memory forever.
+const maxPooledBufferSize = 1 << 16
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBufferSize {
+		return
+	}
+	b.Reset()
+	bufferPool.Put(b)
+}
+
+// Dir returns an indented outline of the element structure for debugging.
+// See Dir for details.
+func (n *Node) Dir(maxDepth int) string {
+	return Dir(n.backing, maxDepth)
+}
+
+// HTML renders the node and its children to a string.
+func (n *Node) HTML() (string, error) {
+	return HTML(n.backing)
+}
+
+// InnerHTML renders the children of the node to a string.
+// See InnerHTML for details.
+func (n *Node) InnerHTML() (string, error) {
+	return InnerHTML(n.backing)
+}
+
+// OuterHTML renders the node including its own tags to a string. It is the same as HTML.
+func (n *Node) OuterHTML() (string, error) {
+	return HTML(n.backing)
+}
+
+// Render renders the node and its children to w.
+// Like HTML, it keeps the source order of attributes.
+func (n *Node) Render(w io.Writer) error {
+	return html.Render(w, n.backing)
+}
+
+// HTML renders the node and its children to a string.
+// The intermediate buffers are pooled to keep allocations low when rendering many nodes.
+//
+// Attributes are rendered in their source order. The functions of this package that change
+// attributes, like Sanitize, keep the order of the remaining attributes, so that an unchanged
+// or minimally changed document renders with a stable attribute order.
+func HTML(node *html.Node) (string, error) {
+	b := getBuffer()
+	defer putBuffer(b)
+	if err := html.Render(b, node); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// InnerHTML renders the children of the node to a string, without the node's own tags.
+// The text of raw text elements like <script> or <style> is written as is, just like HTML does within the element.
+// For void elements like <img> and for text nodes, the result is empty.
+func InnerHTML(node *html.Node) (string, error) {
+	b := getBuffer()
+	defer putBuffer(b)
+	raw := node.Type == html.ElementNode && rawTextElements[node.Data]
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if raw && c.Type == html.TextNode {
+			b.WriteString(c.Data)
+			continue
+		}
+		if err := html.Render(b, c); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// OuterHTML renders the node including its own tags to a string. It is the same as HTML.
+func OuterHTML(node *html.Node) (string, error) {
+	return HTML(node)
+}
+
+// rawTextElements are elements whose text children are rendered without escaping.
+var rawTextElements = map[string]bool{
+	"iframe": true, "noembed": true, "noframes": true, "noscript": true, "plaintext": true,
+	"script": true, "style": true, "xmp": true,
+}
+
+// voidElements are elements that have no end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "keygen": true, "link": true, "meta": true, "param": true, "source": true,
+	"track": true, "wbr": true,
+}
+
+// Tokens returns the token stream of the node's subtree in document order.
+func (n *Node) Tokens() []html.Token {
+	return Tokens(n.backing)
+}
+
+// Tokens returns the token stream of the node's subtree in document order.
+// Elements produce a start tag and an end tag token, except for void elements like <br>, which only produce a start tag.
+// The tokens hold copies of the attributes, so later changes to the tree don't affect them.
+func Tokens(node *html.Node) []html.Token {
+	res := make([]html.Token, 0)
+	appendTokens(node, &res)
+	return res
+}
+
+func appendTokens(node *html.Node, res *[]html.Token) {
+	switch node.Type {
+	case html.DocumentNode:
+	case html.ElementNode:
+		*res = append(*res, html.Token{Type: html.StartTagToken, DataAtom: node.DataAtom, Data: node.Data, Attr: append([]html.Attribute(nil), node.Attr...)})
+		if voidElements[node.Data] {
+			return
+		}
+	case html.TextNode:
+		*res = append(*res, html.Token{Type: html.TextToken, Data: node.Data})
+		return
+	case html.CommentNode:
+		*res = append(*res, html.Token{Type: html.CommentToken, Data: node.Data})
+		return
+	case html.DoctypeNode:
+		*res = append(*res, html.Token{Type: html.DoctypeToken, Data: node.Data})
+		return
+	default:
+		return
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		appendTokens(c, res)
+	}
+	if node.Type == html.ElementNode {
+		*res = append(*res, html.Token{Type: html.EndTagToken, DataAtom: node.DataAtom, Data: node.Data})
+	}
+}
+
+// Dir returns an indented outline of the element structure below the node for debugging, one element per line
+// in CSS notation like div#main.content. Text and all other attributes are left out.
+// Elements deeper than maxDepth levels below the node are omitted. A negative maxDepth means unlimited.
+func Dir(node *html.Node, maxDepth int) string {
+	b := getBuffer()
+	defer putBuffer(b)
+	writeDir(b, node, 0, maxDepth)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func writeDir(b *bytes.Buffer, node *html.Node, depth, maxDepth int) {
+	switch node.Type {
+	case html.DocumentNode:
+		b.WriteString(strings.Repeat("  ", depth))
+		b.WriteString("#document\n")
+	case html.ElementNode:
+		b.WriteString(strings.Repeat("  ", depth))
+		b.WriteString(node.Data)
+		if id := Attr(node, "id"); len(id) > 0 {
+			b.WriteByte('#')
+			b.WriteString(id)
+		}
+		for _, class := range strings.Fields(Attr(node, "class")) {
+			b.WriteByte('.')
+			b.WriteString(class)
+		}
+		b.WriteByte('\n')
+	default:
+		return
+	}
+	if maxDepth >= 0 && depth >= maxDepth {
+		return
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		writeDir(b, c, depth+1, maxDepth)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"bytes"
+	"golang.org/x/net/html"
+	"strings"
+	"testing"
+)
+
+func benchmarkDocument(b *testing.B) *html.Node {
+	var src strings.Builder
+	for i := 0; i < 100; i++ {
+		src.WriteString(`<div class="card"><a href="/item">Item</a><p>Some <b>text</b> here</p></div>`)
+	}
+	root, err := html.Parse(strings.NewReader(src.String()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return root
+}
+
+func BenchmarkHTML(b *testing.B) {
+	root := benchmarkDocument(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := HTML(root); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkHTMLUnpooled is the baseline for BenchmarkHTML, rendering into a new buffer each time.
+func BenchmarkHTMLUnpooled(b *testing.B) {
+	root := benchmarkDocument(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		if err := html.Render(&buf, root); err != nil {
+			b.Fatal(err)
+		}
+		_ = buf.String()
+	}
+}
+
+func TestHTMLKeepsAttributeOrder(t *testing.T) {
+	const doc = `<html><head></head><body><a zeta="1" href="/x" alpha="2" id="link" data-b="3" data-a="4">x</a></body></html>`
+	root, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mustHTML(t, root); got != doc {
+		t.Errorf("HTML() = %s, want %s", got, doc)
+	}
+
+	a := root.FirstWithTagR("a")
+	a.KeepAttrs("data-a", "href", "zeta")
+	if got, want := mustHTML(t, a), `<a zeta="1" href="/x" data-a="4">x</a>`; got != want {
+		t.Errorf("HTML() after KeepAttrs = %s, want %s", got, want)
+	}
+
+	nodes, _ := ParseFragmentString(`<p><a onclick="alert(1)" title="t" href="/x" class="c">x</a></p>`, "")
+	p := nodes[0]
+	p.Sanitize(map[string]bool{"p": true, "a": true}, map[string]bool{"class": true, "href": true, "title": true})
+	if got, want := mustHTML(t, p), `<p><a title="t" href="/x" class="c">x</a></p>`; got != want {
+		t.Errorf("HTML() after Sanitize = %s, want %s", got, want)
+	}
+}
+
+func mustHTML(t *testing.T, n *Node) string {
+	t.Helper()
+	s, err := n.HTML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestInnerAndOuterHTML(t *testing.T) {
+	root, err := Parse(strings.NewReader(`<div id="d">a &amp; b<p class="x"><b>nested</b> text<br></p><img src="i.png"></div>` +
+		`<script>if (a < b && c) { s = "</p>"; }</script><style>a > b { content: "&amp;" }</style>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		node         *Node
+		inner, outer string
+	}{
+		{"nested", root.FirstWithIdR("d"),
+			`a &amp; b<p class="x"><b>nested</b> text<br/></p><img src="i.png"/>`,
+			`<div id="d">a &amp; b<p class="x"><b>nested</b> text<br/></p><img src="i.png"/></div>`},
+		{"void", root.FirstWithTagR("img"), ``, `<img src="i.png"/>`},
+		{"text", newNode(root.FirstWithIdR("d").backing.FirstChild), ``, `a &amp; b`},
+		{"script", root.FirstWithTagR("script"),
+			`if (a < b && c) { s = "</p>"; }`,
+			`<script>if (a < b && c) { s = "</p>"; }</script>`},
+		{"style", root.FirstWithTagR("style"),
+			`a > b { content: "&amp;" }`,
+			`<style>a > b { content: "&amp;" }</style>`},
+	}
+	for _, tt := range tests {
+		inner, err := tt.node.InnerHTML()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inner != tt.inner {
+			t.Errorf("%s: InnerHTML() = %s, want %s", tt.name, inner, tt.inner)
+		}
+		outer, err := tt.node.OuterHTML()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if outer != tt.outer {
+			t.Errorf("%s: OuterHTML() = %s, want %s", tt.name, outer, tt.outer)
+		}
+	}
+}
+
+func TestTokensCopyAttributes(t *testing.T) {
+	nodes, _ := ParseFragmentString(`<a href="/x" title="t" class="c">x</a>`, "")
+	a := nodes[0]
+	tokens := a.Tokens()
+	a.KeepAttrs("class")
+	a.backing.Attr[0].Val = "changed"
+	if got, want := tokens[0].String(), `<a href="/x" title="t" class="c">`; got != want {
+		t.Errorf("token after changing the attributes = %s, want %s", got, want)
+	}
+}
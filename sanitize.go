@@ -0,0 +1,122 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+)
+
+// droppedElements are removed along with their content when they aren't allowed,
+// because their content isn't meant to be displayed as text.
+var droppedElements = map[string]bool{
+	"applet": true, "base": true, "embed": true, "frame": true, "frameset": true, "head": true,
+	"iframe": true, "link": true, "math": true, "meta": true, "noscript": true, "object": true,
+	"script": true, "style": true, "svg": true, "template": true, "title": true,
+}
+
+// KeepAttrs removes all attributes of the node except the given ones.
+// See KeepAttrs for details.
+func (n *Node) KeepAttrs(keys ...string) {
+	KeepAttrs(n.backing, keys...)
+}
+
+// KeepAttrsR is the recursive variant of KeepAttrs.
+func (n *Node) KeepAttrsR(keys ...string) {
+	KeepAttrsR(n.backing, keys...)
+}
+
+// Sanitize removes everything from the node's subtree that isn't explicitly allowed.
+// See Sanitize for details.
+func (n *Node) Sanitize(allowedTags map[string]bool, allowedAttrs map[string]bool) {
+	Sanitize(n.backing, allowedTags, allowedAttrs)
+}
+
+// Sanitize removes everything from the node's subtree that isn't explicitly allowed, so that the result
+// can be rendered into another document. The node itself is kept, but its attributes are sanitized as well.
+//
+// Elements whose tag isn't allowed are unwrapped, that is replaced by their children. Elements that
+// carry no displayable content, like <script>, <style> or <iframe>, are dropped entirely instead.
+// Attributes that aren't allowed are removed. Event handler attributes (on*) and attributes
+// with javascript: or vbscript: URLs are always removed. Comments are removed as well.
+func Sanitize(node *html.Node, allowedTags map[string]bool, allowedAttrs map[string]bool) {
+	sanitizeAttrs(node, allowedAttrs)
+	sanitizeChildren(node, allowedTags, allowedAttrs)
+}
+
+// KeepAttrs removes all attributes of the node except the given ones, e.g. KeepAttrs(node, "href", "src", "alt").
+// The remaining attributes keep their order. Unlike Sanitize, the values of the kept attributes aren't checked.
+func KeepAttrs(node *html.Node, keys ...string) {
+	attrs := node.Attr[:0]
+	for _, a := range node.Attr {
+		for _, k := range keys {
+			if a.Key == k {
+				attrs = append(attrs, a)
+				break
+			}
+		}
+	}
+	node.Attr = attrs
+}
+
+// KeepAttrsR is the recursive variant of KeepAttrs. It applies to the node and all descendant elements.
+func KeepAttrsR(node *html.Node, keys ...string) {
+	KeepAttrs(node, keys...)
+	walk(node, func(c *html.Node) bool {
+		if c.Type != html.ElementNode {
+			return false
+		}
+		KeepAttrs(c, keys...)
+		return true
+	})
+}
+
+func sanitizeChildren(node *html.Node, allowedTags map[string]bool, allowedAttrs map[string]bool) {
+	for c := node.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.ElementNode:
+			switch {
+			case allowedTags[c.Data]:
+				sanitizeAttrs(c, allowedAttrs)
+				sanitizeChildren(c, allowedTags, allowedAttrs)
+			case droppedElements[c.Data]:
+				node.RemoveChild(c)
+			default:
+				sanitizeChildren(c, allowedTags, allowedAttrs)
+				Unwrap(c)
+			}
+		case html.TextNode:
+		default:
+			node.RemoveChild(c)
+		}
+		c = next
+	}
+}
+
+// sanitizeAttrs filters the attributes in place, keeping the order of the remaining ones.
+func sanitizeAttrs(node *html.Node, allowedAttrs map[string]bool) {
+	attrs := node.Attr[:0]
+	for _, a := range node.Attr {
+		key := strings.ToLower(a.Key)
+		if !allowedAttrs[key] || strings.HasPrefix(key, "on") || isScriptURL(a.Val) {
+			continue
+		}
+		attrs = append(attrs, a)
+	}
+	node.Attr = attrs
+}
+
+// isScriptURL returns true if the value is a javascript: or vbscript: URL. Browsers ignore
+// whitespace and control characters within the scheme, so they are ignored here as well.
+func isScriptURL(val string) bool {
+	scheme := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, val)
+	return hasPrefixFold(scheme, "javascript:") || hasPrefixFold(scheme, "vbscript:")
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+	"testing"
+)
+
+var (
+	testAllowedTags  = map[string]bool{"p": true, "a": true, "b": true, "img": true}
+	testAllowedAttrs = map[string]bool{"href": true, "src": true, "title": true, "onclick": true, "onmouseover": true}
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name, fragment, want string
+	}{
+		{"allowed", `<p title="t"><a href="/x">x</a></p>`, `<p title="t"><a href="/x">x</a></p>`},
+		{"attribute not allowed", `<p class="c" title="t">x</p>`, `<p title="t">x</p>`},
+		{"event handler", `<a href="/x" onclick="f()" OnMouseOver="g()">x</a>`, `<a href="/x">x</a>`},
+		{"javascript", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript mixed case", `<a href="JavaScript:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript leading whitespace", "<a href=\"  \tjavascript:alert(1)\">x</a>", `<a>x</a>`},
+		{"javascript tab entity", `<a href="java&#x09;script:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript newline entity", `<a href="java&NewLine;script:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript character reference", `<a href="&#106;avascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"vbscript", `<img src="VBScript:msgbox(1)">`, `<img/>`},
+		{"other scheme", `<a href="https://example.com/javascript:">x</a>`, `<a href="https://example.com/javascript:">x</a>`},
+		{"script", `<p>a<script>alert(1)</script>b</p>`, `<p>ab</p>`},
+		{"style", `<p>a<style>p { color: red }</style>b</p>`, `<p>ab</p>`},
+		{"iframe", `<p>a<iframe src="https://example.com">fallback</iframe>b</p>`, `<p>ab</p>`},
+		{"svg", `<p>a<svg><script>alert(1)</script><text>t</text></svg>b</p>`, `<p>ab</p>`},
+		{"unknown tags", `<custom-tag><p>a <font color="red">b</font></p></custom-tag>`, `<p>a b</p>`},
+		{"unwrapped tag keeps sanitized children", `<span><a href="javascript:x" onclick="f()">x</a></span>`, `<a>x</a>`},
+		{"comment", `<p>a<!-- secret -->b</p>`, `<p>ab</p>`},
+	}
+	for _, tt := range tests {
+		nodes, err := ParseFragmentString(`<div>`+tt.fragment+`</div>`, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[0].Sanitize(testAllowedTags, testAllowedAttrs)
+		got, err := nodes[0].InnerHTML()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: Sanitize(%s) = %s, want %s", tt.name, tt.fragment, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeDocument(t *testing.T) {
+	root, err := Parse(strings.NewReader(`<!DOCTYPE html><!-- c --><html><head><title>t</title></head><body><p>a</p></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.Sanitize(testAllowedTags, testAllowedAttrs)
+	if got, want := mustHTML(t, root), `<p>a</p>`; got != want {
+		t.Errorf("HTML() = %s, want %s", got, want)
+	}
+}
+
+func TestSanitizeUppercaseEventHandler(t *testing.T) {
+	node := &html.Node{Type: html.ElementNode, Data: "a", Attr: []html.Attribute{
+		{Key: "ONCLICK", Val: "f()"}, {Key: "href", Val: "/x"}, {Key: "onClick", Val: "g()"},
+	}}
+	Sanitize(node, testAllowedTags, testAllowedAttrs)
+	if len(node.Attr) != 1 || node.Attr[0].Key != "href" {
+		t.Errorf("attributes after Sanitize = %v, want only href", node.Attr)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+// Selection is a list of nodes, usually the result of a query.
+// Any result can be turned into a Selection with a conversion, e.g. Selection(n.SelectAll(selector)).
+type Selection []*Node
+
+// Each calls fn for each node in the selection along with its index.
+func (s Selection) Each(fn func(i int, n *Node)) {
+	for i, n := range s {
+		fn(i, n)
+	}
+}
+
+// Filter returns the nodes in the selection that match the CSS selector.
+// A selector that can't be parsed matches nothing, so the result is empty. Use Compile to check it.
+func (s Selection) Filter(sel string) Selection {
+	cs, err := Compile(sel)
+	if err != nil {
+		return Selection{}
+	}
+	return s.filter(cs, true)
+}
+
+// Map returns the result of fn for each node in the selection.
+func (s Selection) Map(fn func(*Node) string) []string {
+	res := make([]string, 0, len(s))
+	for _, n := range s {
+		res = append(res, fn(n))
+	}
+	return res
+}
+
+// MapText returns the text of each node in the selection as returned by TextContentR.
+func (s Selection) MapText() []string {
+	return s.Map(func(n *Node) string { return n.TextContentR() })
+}
+
+// Not returns the nodes in the selection that don't match the CSS selector.
+// A selector that can't be parsed matches nothing, so all nodes are returned. Use Compile to check it.
+func (s Selection) Not(sel string) Selection {
+	cs, err := Compile(sel)
+	if err != nil {
+		return s
+	}
+	return s.filter(cs, false)
+}
+
+func (s Selection) filter(cs *CompiledSelector, keep bool) Selection {
+	res := make(Selection, 0, len(s))
+	for _, n := range s {
+		if cs.Matches(n) == keep {
+			res = append(res, n)
+		}
+	}
+	return res
+}
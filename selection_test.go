@@ -0,0 +1,41 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectionFilterAndNot(t *testing.T) {
+	nodes, err := ParseFragmentString(`<p id="a" class="x"></p><p id="b"></p><div id="c" class="x"></div>`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Selection(nodes)
+	ids := func(s Selection) string {
+		return strings.Join(s.Map(func(n *Node) string { return n.Attr("id") }), " ")
+	}
+	tests := []struct {
+		selector    string
+		filter, not string
+	}{
+		{".x", "a c", "b"},
+		{"p", "a b", "c"},
+		{"p.x, div", "a c", "b"},
+		{"span", "", "a b c"},
+		{"p >", "", "a b c"},
+		{"[id", "", "a b c"},
+		{"", "", "a b c"},
+	}
+	for _, tt := range tests {
+		if got := ids(s.Filter(tt.selector)); got != tt.filter {
+			t.Errorf("Filter(%q) = %q, want %q", tt.selector, got, tt.filter)
+		}
+		if got := ids(s.Not(tt.selector)); got != tt.not {
+			t.Errorf("Not(%q) = %q, want %q", tt.selector, got, tt.not)
+		}
+	}
+}
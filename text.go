@@ -0,0 +1,535 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TextOptions control how Text extracts text. The zero value returns the direct text children as is.
+//
+// For <div><p>Hello <b>big</b>   world</p><p>!</p></div>, the options yield
+//
+//	TextOptions{Recursive: true}                                    "Hello big   world!"
+//	TextOptions{Recursive: true, Trim: true}                        "Hello big world !"
+//	TextOptions{Recursive: true, CollapseWhitespace: true}          "Hello big world!"
+//	TextOptions{Recursive: true, Trim: true, BlockSeparator: "\n"} "Hello big world\n!"
+type TextOptions struct {
+	// Include the text of all descendants instead of only the direct text children.
+	Recursive bool
+	// Trim each text node and join the non-empty ones with a single space.
+	// Otherwise, the text nodes are concatenated as is.
+	Trim bool
+	// Replace each sequence of whitespace with a single space.
+	CollapseWhitespace bool
+	// Skip the contents of <script>, <style> and <noscript> elements.
+	SkipScripts bool
+	// Separate the text of block-level elements like <p> or <div> with the given string, e.g. "\n".
+	// Only applies to recursive extraction.
+	BlockSeparator string
+}
+
+// TextSegment is a chunk of text along with the element that contains it.
+type TextSegment struct {
+	// Text is the trimmed text of a single text node.
+	Text string
+	// Node is the parent element of the text node or nil if the text node has no parent.
+	Node *Node
+}
+
+// DefaultTextSkipTags are the elements whose content TextContentR, VisibleText and related functions skip
+// unless WithTextSkipTags says otherwise. Their content is code, metadata or inert markup rather than text.
+// Changing the list changes the default for all later calls, so it should only be done during initialization.
+var DefaultTextSkipTags = []string{"head", "noscript", "script", "style", "template"}
+
+// TextContentOption configures the text extraction of TextContentR, VisibleText, TextRuns and TextSegments.
+type TextContentOption func(*textConfig)
+
+// WithTextSkipTags replaces DefaultTextSkipTags for a single call. The content of elements with one of the tags
+// is skipped. WithTextSkipTags() without tags includes the text of all elements, including scripts and styles.
+func WithTextSkipTags(tags ...string) TextContentOption {
+	return func(c *textConfig) {
+		c.skipTags = make(map[string]bool, len(tags))
+		for _, t := range tags {
+			c.skipTags[t] = true
+		}
+	}
+}
+
+type textConfig struct {
+	skipTags map[string]bool
+}
+
+// textSkip returns a function that returns true for the descendants of node that are skipped according to the options.
+// The node itself isn't skipped because of its tag, so that e.g. TextContentR of a <style> returns the style sheet.
+// hidden, if not nil, is checked for all elements including node.
+// Without options, DefaultTextSkipTags is searched directly instead of building a map on every call.
+func textSkip(node *html.Node, hidden func(*html.Node) bool, opts []TextContentOption) func(*html.Node) bool {
+	c := textConfig{}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return func(n *html.Node) bool {
+		if hidden != nil && hidden(n) {
+			return true
+		}
+		if n == node || n.Type != html.ElementNode {
+			return false
+		}
+		if c.skipTags == nil {
+			return isDefaultTextSkipTag(n.Data)
+		}
+		return c.skipTags[n.Data]
+	}
+}
+
+func isDefaultTextSkipTag(tag string) bool {
+	for _, t := range DefaultTextSkipTags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ScriptData returns the raw, untrimmed text of a <script> or <style> element.
+func (n *Node) ScriptData() string {
+	return ScriptData(n.backing)
+}
+
+// Text extracts text as configured by the options.
+// See TextOptions for details.
+func (n *Node) Text(opts TextOptions) string {
+	return Text(n.backing, opts)
+}
+
+// TextContentR joins the trimmed text of all descendant text nodes with a single space.
+// Unlike TextContent, it skips the content of the DefaultTextSkipTags.
+// See TextContentR for details.
+func (n *Node) TextContentR(opts ...TextContentOption) string {
+	return TextContentR(n.backing, opts...)
+}
+
+// TextDecoded is like TextContentR but additionally decodes HTML entities.
+// See TextDecoded for details.
+func (n *Node) TextDecoded() string {
+	return TextDecoded(n.backing)
+}
+
+// TextEscaped returns the text of the node's subtree HTML-escaped.
+// See TextEscaped for details.
+func (n *Node) TextEscaped() string {
+	return TextEscaped(n.backing)
+}
+
+// TextLength returns the number of characters in the trimmed text of all descendant text nodes.
+func (n *Node) TextLength() int {
+	return TextLength(n.backing)
+}
+
+// TextRuns returns the trimmed text of each descendant text node.
+// See TextRuns for details.
+func (n *Node) TextRuns(opts ...TextContentOption) []string {
+	return TextRuns(n.backing, opts...)
+}
+
+// TextSegments returns the visible text of the node's subtree as segments that point to the elements they belong to.
+// See TextSegments for details.
+func (n *Node) TextSegments(opts ...TextContentOption) []TextSegment {
+	return TextSegments(n.backing, opts...)
+}
+
+// VisibleText is like TextContentR but skips elements that are hidden
+// by the hidden attribute, aria-hidden="true" or an inline display:none or visibility:hidden style.
+func (n *Node) VisibleText(opts ...TextContentOption) string {
+	return VisibleText(n.backing, opts...)
+}
+
+// FindAllByText returns all descendant elements with a direct text child containing text.
+// See FindAllByText for details.
+func (n *Node) FindAllByText(text string, limit int) []*Node {
+	return newNodes(FindAllByText(n.backing, text, limit))
+}
+
+// AllWithMinText returns all descendants with the given tag that contain at least minLen characters of text.
+// See AllWithMinText for details.
+func (n *Node) AllWithMinText(tagName string, minLen int) []*Node {
+	return newNodes(AllWithMinText(n.backing, tagName, minLen))
+}
+
+// InnerText returns the visible text of the node's subtree, similar to innerText in browsers.
+// See InnerText for details.
+func (n *Node) InnerText() string {
+	return InnerText(n.backing)
+}
+
+// PlainText renders the text of the node's subtree as readable plain text.
+// Block elements start a new line, <br> produces a line break and list items are prefixed with a dash.
+// Text within a line is joined with single spaces. Scripts, styles, templates and the document head are skipped.
+func (n *Node) PlainText() string {
+	return PlainText(n.backing)
+}
+
+// ScriptData returns the raw, untrimmed text of a <script> or <style> element.
+// The parser stores the contents of these elements as a single text child. For other elements,
+// the text of all direct text children is returned as is.
+func ScriptData(node *html.Node) string {
+	var b strings.Builder
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	}
+	return b.String()
+}
+
+// Text extracts text as configured by the options.
+// See TextOptions for details.
+func Text(node *html.Node, opts TextOptions) string {
+	b := &textBuilder{opts: opts}
+	if node.Type == html.TextNode {
+		b.add(node.Data)
+	} else if opts.Recursive {
+		b.addChildren(node)
+	} else {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode {
+				b.add(c.Data)
+			}
+		}
+	}
+	return b.String()
+}
+
+var scriptElements = map[string]bool{
+	"noscript": true, "script": true, "style": true,
+}
+
+type textBuilder struct {
+	opts  TextOptions
+	b     strings.Builder
+	block bool
+}
+
+func (b *textBuilder) addChildren(node *html.Node) {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.TextNode:
+			b.add(c.Data)
+		case html.ElementNode:
+			if b.opts.SkipScripts && scriptElements[c.Data] {
+				continue
+			}
+			block := blockElements[c.Data]
+			b.block = b.block || block
+			b.addChildren(c)
+			b.block = b.block || block
+		}
+	}
+}
+
+func (b *textBuilder) add(s string) {
+	if b.opts.CollapseWhitespace {
+		s = collapseWhitespace(s)
+	}
+	if b.opts.Trim {
+		s = trim(s)
+		if len(s) == 0 {
+			return
+		}
+	}
+	if b.b.Len() > 0 {
+		if b.block && len(b.opts.BlockSeparator) > 0 {
+			b.b.WriteString(b.opts.BlockSeparator)
+		} else if b.opts.Trim {
+			b.b.WriteByte(' ')
+		}
+	}
+	b.block = false
+	b.b.WriteString(s)
+}
+
+func (b *textBuilder) String() string {
+	return b.b.String()
+}
+
+// collapseWhitespace replaces each sequence of whitespace with a single space.
+func collapseWhitespace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// TextContentR joins the trimmed text of all descendant text nodes with a single space.
+// Unlike TextContent, which concatenates the text as is, each text node is trimmed and empty ones are dropped.
+// The content of descendants with one of the DefaultTextSkipTags, like <script> and <style>, is skipped.
+// Use WithTextSkipTags to skip other elements instead.
+func TextContentR(node *html.Node, opts ...TextContentOption) string {
+	return strings.Join(textRuns(node, textSkip(node, nil, opts)), " ")
+}
+
+// TextDecoded is like TextContentR but additionally decodes HTML entities in the text.
+// The parser already decodes entities in regular text, so the result only differs for text it keeps raw:
+// the contents of <script>, <style> and similar elements, and text that was encoded twice in the markup,
+// like "&amp;lt;". Unlike TextContentR, no elements are skipped, so that the decoded scripts and styles are included.
+// Called on a comment node, TextDecoded returns the decoded comment.
+func TextDecoded(node *html.Node) string {
+	if node.Type == html.CommentNode {
+		return html.UnescapeString(trim(node.Data))
+	}
+	return html.UnescapeString(TextContentR(node, WithTextSkipTags()))
+}
+
+// TextEscaped returns the text as returned by TextContentR with <, >, &, ' and " escaped, so that it can be inserted
+// into another HTML document as text or as a quoted attribute value. Markup in the scraped text is shown rather than run.
+func TextEscaped(node *html.Node) string {
+	return html.EscapeString(TextContentR(node))
+}
+
+// TextLength returns the number of characters in the trimmed text of all descendant text nodes.
+// Like TextContentR, the content of descendants with one of the DefaultTextSkipTags is skipped.
+// It is cheaper than measuring TextContentR because no string is built.
+func TextLength(node *html.Node) int {
+	if node.Type == html.TextNode {
+		return utf8.RuneCountInString(trim(node.Data))
+	}
+	return textLength(node)
+}
+
+// textLength is TextLength for the children of node. It doesn't use visitText so that measuring
+// many candidates, like AllWithMinText does, doesn't allocate.
+func textLength(node *html.Node) int {
+	length := 0
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.TextNode:
+			length += utf8.RuneCountInString(trim(c.Data))
+		case html.ElementNode:
+			if !isDefaultTextSkipTag(c.Data) {
+				length += textLength(c)
+			}
+		}
+	}
+	return length
+}
+
+// TextRuns returns the trimmed text of each descendant text node in document order, skipping text nodes
+// that are empty after trimming. Joining the runs with a single space yields TextContentR with the same options.
+func TextRuns(node *html.Node, opts ...TextContentOption) []string {
+	return textRuns(node, textSkip(node, nil, opts))
+}
+
+// TextSegments returns the visible text of the node's subtree as segments that point to the elements they belong to.
+// There is one segment per non-empty text node, in document order. Joining the texts with a single space
+// yields VisibleText with the same options, so a position in that text can be mapped back to the element that produced it.
+func TextSegments(node *html.Node, opts ...TextContentOption) []TextSegment {
+	res := make([]TextSegment, 0)
+	visitText(node, textSkip(node, isHidden, opts), func(n *html.Node, t string) {
+		var parent *Node
+		if n.Parent != nil {
+			parent = newNode(n.Parent)
+		}
+		res = append(res, TextSegment{Text: t, Node: parent})
+	})
+	return res
+}
+
+// VisibleText is like TextContentR but skips elements that are hidden
+// by the hidden attribute, aria-hidden="true" or an inline display:none or visibility:hidden style.
+// Like TextContentR, the content of descendants with one of the DefaultTextSkipTags is skipped as well.
+func VisibleText(node *html.Node, opts ...TextContentOption) string {
+	return strings.Join(textRuns(node, textSkip(node, isHidden, opts)), " ")
+}
+
+// FindAllByText returns all descendant elements with a direct text child containing text.
+// Only direct text children are considered, so the ancestors of a match don't match as well.
+// The elements are returned in document order, that is a pre-order traversal. If limit is greater
+// than 0, the traversal stops after limit elements have been found.
+func FindAllByText(node *html.Node, text string, limit int) []*html.Node {
+	return selectAll(node, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode && strings.Contains(c.Data, text) {
+				return true
+			}
+		}
+		return false
+	}, true, limit)
+}
+
+// AllWithMinText returns all descendants with the given tag in document order whose text, as measured by TextLength,
+// has at least minLen characters. Nested matches are all returned, so an <article> and a long <div> inside of it
+// are both candidates for content extraction.
+func AllWithMinText(node *html.Node, tagName string, minLen int) []*html.Node {
+	return selectAll(node, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == tagName && TextLength(n) >= minLen
+	}, true, 0)
+}
+
+// InnerText returns the visible text of the node's subtree, similar to innerText in browsers.
+// Like PlainText, block elements start a new line and <br> produces a line break, but list items
+// aren't prefixed and elements hidden as described for VisibleText are skipped.
+func InnerText(node *html.Node) string {
+	w := &lineWriter{}
+	w.write(node, isHidden)
+	return w.String()
+}
+
+// PlainText renders the text of the node's subtree as readable plain text.
+// Block elements start a new line, <br> produces a line break and list items are prefixed with a dash.
+// Text within a line is joined with single spaces. Scripts, styles, templates and the document head are skipped.
+func PlainText(node *html.Node) string {
+	w := &lineWriter{bullets: true}
+	w.write(node, nil)
+	return w.String()
+}
+
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "caption": true,
+	"dd": true, "details": true, "dialog": true, "div": true, "dl": true, "dt": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hgroup": true, "hr": true, "legend": true, "li": true, "main": true,
+	"nav": true, "ol": true, "p": true, "pre": true, "section": true, "summary": true,
+	"table": true, "tr": true, "ul": true,
+}
+
+var nonRenderedElements = map[string]bool{
+	"head": true, "noscript": true, "script": true, "style": true, "template": true,
+}
+
+// lineWriter collects text as lines of space separated words.
+type lineWriter struct {
+	// bullets prefixes list items with a dash
+	bullets bool
+	lines   []string
+	words   []string
+}
+
+// write adds the text of the node's subtree. Elements for which skip returns true are pruned.
+func (w *lineWriter) write(node *html.Node, skip func(*html.Node) bool) {
+	switch node.Type {
+	case html.TextNode:
+		w.words = append(w.words, strings.Fields(node.Data)...)
+		return
+	case html.ElementNode:
+		if nonRenderedElements[node.Data] || (skip != nil && skip(node)) {
+			return
+		}
+		if node.Data == "br" {
+			w.breakLine(true)
+			return
+		}
+	case html.DocumentNode:
+	default:
+		return
+	}
+	block := node.Type == html.ElementNode && blockElements[node.Data]
+	if block {
+		w.breakLine(false)
+	}
+	if w.bullets && node.Data == "li" {
+		w.words = append(w.words, "-")
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		w.write(c, skip)
+	}
+	if block {
+		w.breakLine(false)
+	}
+}
+
+// breakLine ends the current line. Empty lines are only added if hard is true.
+func (w *lineWriter) breakLine(hard bool) {
+	if len(w.words) > 0 || hard {
+		w.lines = append(w.lines, strings.Join(w.words, " "))
+		w.words = nil
+	}
+}
+
+func (w *lineWriter) String() string {
+	w.breakLine(false)
+	return strings.Join(w.lines, "\n")
+}
+
+// textRuns returns the trimmed, non-empty text of all text nodes in the subtree of node.
+// Elements for which skip returns true are pruned along with their children.
+func textRuns(node *html.Node, skip func(*html.Node) bool) []string {
+	res := make([]string, 0)
+	visitText(node, skip, func(_ *html.Node, t string) {
+		res = append(res, t)
+	})
+	return res
+}
+
+// visitText calls fn with each text node in the subtree of node, in document order, and its trimmed text.
+// Text nodes that are empty after trimming are skipped.
+// Elements for which skip returns true are pruned along with their children.
+func visitText(node *html.Node, skip func(*html.Node) bool, fn func(n *html.Node, text string)) {
+	add := func(n *html.Node) {
+		if t := trim(n.Data); len(t) > 0 {
+			fn(n, t)
+		}
+	}
+	if node.Type == html.TextNode {
+		add(node)
+		return
+	}
+	if skip != nil && skip(node) {
+		return
+	}
+	walk(node, func(c *html.Node) bool {
+		switch c.Type {
+		case html.TextNode:
+			add(c)
+			return false
+		case html.ElementNode:
+			return skip == nil || !skip(c)
+		}
+		return false
+	})
+}
+
+// isHidden returns true if the element is hidden by the hidden attribute,
+// aria-hidden="true" or an inline display:none or visibility:hidden style.
+func isHidden(node *html.Node) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+	if hasAttr(node, "hidden") || Attr(node, "aria-hidden") == "true" {
+		return true
+	}
+	if !hasAttr(node, "style") {
+		return false
+	}
+	style := Style(node)
+	return styleIs(style["display"], "none") || styleIs(style["visibility"], "hidden")
+}
+
+// styleIs returns true if the style value is the keyword, ignoring case and !important.
+func styleIs(val, keyword string) bool {
+	return strings.EqualFold(strings.TrimSpace(strings.TrimSuffix(val, "!important")), keyword)
+}
+
+// trim removes all leading and trailing Unicode whitespace, including the non-breaking spaces of &nbsp;.
+func trim(s string) string {
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTextTrimsUnicodeWhitespace(t *testing.T) {
+	root, err := Parse(strings.NewReader("<p>&nbsp;x&nbsp;</p><p>\f y \r\n</p><p> z <b>&nbsp;</b></p>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := root.TextContentR(), "x y z"; got != want {
+		t.Errorf("TextContentR() = %q, want %q", got, want)
+	}
+	if got, want := root.VisibleText(), "x y z"; got != want {
+		t.Errorf("VisibleText() = %q, want %q", got, want)
+	}
+	if got, want := strings.Join(root.TextRuns(), "|"), "x|y|z"; got != want {
+		t.Errorf("TextRuns() = %q, want %q", got, want)
+	}
+	if got, want := root.TextLength(), 3; got != want {
+		t.Errorf("TextLength() = %d, want %d", got, want)
+	}
+	if got, want := Selection(root.AllWithTagR("p")).MapText(), []string{"x", "y", "z"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("MapText() = %q, want %q", got, want)
+	}
+}
+
+func TestTextLengthMatchesTextRuns(t *testing.T) {
+	root, err := Parse(strings.NewReader(`<title>t</title><div> ä <b>bc</b><script>x</script><style>y</style>` +
+		`<noscript>z</noscript><template>w</template><!-- c --><p>d&nbsp;</p></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	walk(root.backing, func(n *html.Node) bool {
+		if got, want := TextLength(n), utf8.RuneCountInString(strings.Join(TextRuns(n), "")); got != want {
+			t.Errorf("TextLength of %q = %d, want %d", n.Data, got, want)
+		}
+		return true
+	})
+}
+
+func BenchmarkTextLength(b *testing.B) {
+	node := benchmarkDocument(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		TextLength(node)
+	}
+}
+
+func BenchmarkAllWithMinText(b *testing.B) {
+	node := benchmarkDocument(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		AllWithMinText(node, "div", 10)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"sort"
+	"unicode/utf8"
+)
+
+// ParseWarning describes a problem in the markup that the parser silently recovered from.
+type ParseWarning struct {
+	// Line is the 1-based line of the offending token.
+	Line int
+	// Col is the 1-based column of the offending token, counted in characters.
+	Col int
+	// Msg describes the problem.
+	Msg string
+}
+
+func (w ParseWarning) String() string {
+	return fmt.Sprintf("%d:%d: %s", w.Line, w.Col, w.Msg)
+}
+
+// optionalEndTags are elements whose end tag may be omitted.
+var optionalEndTags = map[string]bool{
+	"body": true, "caption": true, "colgroup": true, "dd": true, "dt": true, "head": true, "html": true,
+	"li": true, "optgroup": true, "option": true, "p": true, "rb": true, "rp": true, "rt": true, "rtc": true,
+	"tbody": true, "td": true, "tfoot": true, "th": true, "thead": true, "tr": true,
+}
+
+// ParseWithWarnings is like Parse but additionally reports the position of problems in the markup,
+// like stray end tags, unclosed or misnested elements and duplicate attributes. The warnings are ordered by position.
+// The checks are based on the token stream and are much simpler than the parser's error recovery,
+// so they point at likely problems rather than being a full validation.
+func ParseWithWarnings(r io.Reader) (*Node, []ParseWarning, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	return newNode(root), checkTokens(data), nil
+}
+
+// checkTokens tokenizes data and returns warnings for suspicious tokens.
+func checkTokens(data []byte) []ParseWarning {
+	lines := lineOffsets(data)
+	warnings := make([]ParseWarning, 0)
+	warn := func(offset int, format string, args ...any) {
+		line := sort.Search(len(lines), func(i int) bool { return lines[i] > offset })
+		col := utf8.RuneCount(data[lines[line-1]:offset]) + 1
+		warnings = append(warnings, ParseWarning{Line: line, Col: col, Msg: fmt.Sprintf(format, args...)})
+	}
+	type openElement struct {
+		tag    string
+		offset int
+	}
+	stack := make([]openElement, 0)
+	z := html.NewTokenizer(bytes.NewReader(data))
+	offset := 0
+	for {
+		tt := z.Next()
+		start := offset
+		offset += len(z.Raw())
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				warn(start, "%v", z.Err())
+			}
+			for i := len(stack) - 1; i >= 0; i-- {
+				if !optionalEndTags[stack[i].tag] {
+					warn(stack[i].offset, "unclosed <%s>", stack[i].tag)
+				}
+			}
+			sort.SliceStable(warnings, func(i, j int) bool {
+				if warnings[i].Line != warnings[j].Line {
+					return warnings[i].Line < warnings[j].Line
+				}
+				return warnings[i].Col < warnings[j].Col
+			})
+			return warnings
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			seen := make(map[string]bool, len(t.Attr))
+			for _, a := range t.Attr {
+				if seen[a.Key] {
+					warn(start, "duplicate attribute %q on <%s>", a.Key, t.Data)
+				}
+				seen[a.Key] = true
+			}
+			if tt == html.StartTagToken && !voidElements[t.Data] {
+				stack = append(stack, openElement{tag: t.Data, offset: start})
+			}
+		case html.EndTagToken:
+			t := z.Token()
+			i := len(stack) - 1
+			for i >= 0 && stack[i].tag != t.Data {
+				i--
+			}
+			if i < 0 {
+				warn(start, "stray end tag </%s>", t.Data)
+				continue
+			}
+			for _, e := range stack[i+1:] {
+				if !optionalEndTags[e.tag] {
+					warn(e.offset, "<%s> is closed by </%s>", e.tag, t.Data)
+				}
+			}
+			stack = stack[:i]
+		}
+	}
+}
+
+// lineOffsets returns the offsets at which the lines in data start.
+func lineOffsets(data []byte) []int {
+	res := []int{0}
+	for i, b := range data {
+		if b == '\n' {
+			res = append(res, i+1)
+		}
+	}
+	return res
+}
@@ -0,0 +1,46 @@
+// Copyright 2023 Christoph Fichtmüller. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package soup
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseWithWarnings(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{"valid", "<html><body><div><p>a<br>b</div><ul><li>x</ul></body></html>", nil},
+		{"stray end tag", "<div>a</span></div>", []string{"1:7: stray end tag </span>"}},
+		{"closed by other end tag", "<div>\n  <span>a</div>", []string{"2:3: <span> is closed by </div>"}},
+		{"duplicate attribute", `<p>x</p><a href="a" id="x" href="b">y</a>`, []string{`1:9: duplicate attribute "href" on <a>`}},
+		{"unclosed at EOF", "<div>\n<section><p>a", []string{"1:1: unclosed <div>", "2:1: unclosed <section>"}},
+		{"multi-byte characters", "<p>äöü €</p><b>x</i></b>", []string{"1:17: stray end tag </i>"}},
+		{"multi-byte characters on later line", "ü\nä😀<em>x</b></em>", []string{"2:8: stray end tag </b>"}},
+		{"ordered by position", "<div>\n<span></b>\n</div>", []string{
+			"2:1: <span> is closed by </div>",
+			"2:7: stray end tag </b>",
+		}},
+	}
+	for _, tt := range tests {
+		root, warnings, err := ParseWithWarnings(strings.NewReader(tt.doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root == nil {
+			t.Fatalf("%s: no document", tt.name)
+		}
+		got := make([]string, 0, len(warnings))
+		for _, w := range warnings {
+			got = append(got, w.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: warnings = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}